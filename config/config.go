@@ -0,0 +1,370 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CassioRoos/MicroseService/data"
+	"github.com/CassioRoos/MicroseService/logging"
+	"github.com/CassioRoos/MicroseService/tracing"
+	"github.com/hashicorp/go-hclog"
+	"gopkg.in/yaml.v2"
+)
+
+// EnvFile is the environment variable naming the optional config file
+const EnvFile = "CONFIG_FILE"
+
+// redacted replaces the value of the secrets when the config is printed
+const redacted = "[REDACTED]"
+
+// Config is the configuration of the service. The defaults are overridden
+// by the optional YAML or JSON file, then by the environment variables
+type Config struct {
+	Server   Server   `yaml:"server"`
+	Currency Currency `yaml:"currency"`
+	Store    Store    `yaml:"store"`
+	Log      Log      `yaml:"log"`
+	Trace    Trace    `yaml:"trace"`
+	Admin    Admin    `yaml:"admin"`
+}
+
+// Server is the configuration of the HTTP server
+type Server struct {
+	// Bind address of the server, like :8888
+	BindAddress string `yaml:"bind_address"`
+	// Max time to read a request, body included
+	ReadTimeout time.Duration `yaml:"read_timeout"`
+	// Max time to write a response
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	// Max time a keep-alive connection waits for the next request
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+	// Max time the running requests have to complete on shutdown
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// Time every readiness check has to complete
+	ReadyTimeout time.Duration `yaml:"ready_timeout"`
+	// Origins allowed by CORS, * allows any origin
+	CORSOrigins []string `yaml:"cors_origins"`
+}
+
+// Currency is the configuration of the currency server client
+type Currency struct {
+	// Address of the gRPC currency server
+	Address string `yaml:"address"`
+	// ISO 4217 code of the prices of cars stored without a currency
+	Base string `yaml:"base"`
+	// Deadline of every call to the currency server, 0 waits for the request to end
+	RateTimeout time.Duration `yaml:"rate_timeout"`
+	// Age after which a cached rate is stale, 0 never expires
+	RateMaxAge time.Duration `yaml:"rate_max_age"`
+	// What to do with stale rates, serve, refetch or fail
+	RateStalePolicy string `yaml:"rate_stale_policy"`
+	// Consecutive failures that open the circuit breaker
+	BreakerThreshold int `yaml:"breaker_threshold"`
+	// Time the circuit breaker stays open
+	BreakerCoolDown time.Duration `yaml:"breaker_cool_down"`
+	// Interval between the health probes of the server
+	HealthInterval time.Duration `yaml:"health_interval"`
+}
+
+// Store is the configuration of the car store
+type Store struct {
+	// memory or file
+	Type string `yaml:"type"`
+	// File of the file store
+	Path string `yaml:"path"`
+}
+
+// Log is the configuration of the logger, see logging.Config
+type Log struct {
+	Name       string `yaml:"name"`
+	Level      string `yaml:"level"`
+	Format     string `yaml:"format"`
+	TimeFormat string `yaml:"time_format"`
+	Path       string `yaml:"path"`
+}
+
+// Trace is the configuration of the tracing, see tracing.Config
+type Trace struct {
+	Exporter    string  `yaml:"exporter"`
+	Path        string  `yaml:"path"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// Admin is the configuration of the admin endpoints
+type Admin struct {
+	// Bearer token required by the admin endpoints, empty disables them
+	Token string `yaml:"token"`
+}
+
+// Default returns the configuration used when nothing is set
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			BindAddress:     ":8888",
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    5 * time.Second,
+			IdleTimeout:     120 * time.Second,
+			ShutdownTimeout: 30 * time.Second,
+			ReadyTimeout:    2 * time.Second,
+			CORSOrigins:     []string{"*"},
+		},
+		Currency: Currency{
+			Address:          "localhost:9098",
+			Base:             data.DefaultCurrency,
+			RateTimeout:      2 * time.Second,
+			RateMaxAge:       time.Hour,
+			RateStalePolicy:  string(data.StaleServe),
+			BreakerThreshold: 5,
+			BreakerCoolDown:  30 * time.Second,
+			HealthInterval:   5 * time.Second,
+		},
+		Store: Store{
+			Type: data.StoreMemory,
+			Path: "cars.json",
+		},
+		Log: Log{
+			Name:       "cassio.roos-api++>",
+			Level:      "debug",
+			Format:     logging.FormatJSON,
+			TimeFormat: hclog.TimeFormat,
+		},
+		Trace: Trace{
+			Exporter:    tracing.ExporterNone,
+			Path:        "traces.json",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// field binds a setting to its environment variable
+type field struct {
+	env string
+	// path of the setting in the config file
+	key string
+	// pointer to the setting
+	value  interface{}
+	secret bool
+}
+
+// fields lists every setting of the config with its environment variable
+func (c *Config) fields() []field {
+	return []field{
+		{env: "APP_PORT", key: "server.bind_address", value: &c.Server.BindAddress},
+		{env: "SERVER_READ_TIMEOUT", key: "server.read_timeout", value: &c.Server.ReadTimeout},
+		{env: "SERVER_WRITE_TIMEOUT", key: "server.write_timeout", value: &c.Server.WriteTimeout},
+		{env: "SERVER_IDLE_TIMEOUT", key: "server.idle_timeout", value: &c.Server.IdleTimeout},
+		{env: "SERVER_SHUTDOWN_TIMEOUT", key: "server.shutdown_timeout", value: &c.Server.ShutdownTimeout},
+		{env: "READY_TIMEOUT", key: "server.ready_timeout", value: &c.Server.ReadyTimeout},
+		{env: "CORS_ORIGINS", key: "server.cors_origins", value: &c.Server.CORSOrigins},
+		{env: "GRPC_PORT", key: "currency.address", value: &c.Currency.Address},
+		{env: "BASE_CURRENCY", key: "currency.base", value: &c.Currency.Base},
+		{env: "RATE_TIMEOUT", key: "currency.rate_timeout", value: &c.Currency.RateTimeout},
+		{env: "RATE_MAX_AGE", key: "currency.rate_max_age", value: &c.Currency.RateMaxAge},
+		{env: "RATE_STALE_POLICY", key: "currency.rate_stale_policy", value: &c.Currency.RateStalePolicy},
+		{env: "RATE_BREAKER_THRESHOLD", key: "currency.breaker_threshold", value: &c.Currency.BreakerThreshold},
+		{env: "RATE_BREAKER_COOL_DOWN", key: "currency.breaker_cool_down", value: &c.Currency.BreakerCoolDown},
+		{env: "GRPC_HEALTH_INTERVAL", key: "currency.health_interval", value: &c.Currency.HealthInterval},
+		{env: "STORE_TYPE", key: "store.type", value: &c.Store.Type},
+		{env: "STORE_PATH", key: "store.path", value: &c.Store.Path},
+		{env: "LOG_NAME", key: "log.name", value: &c.Log.Name},
+		{env: "LOG_LEVEL", key: "log.level", value: &c.Log.Level},
+		{env: "LOG_FORMAT", key: "log.format", value: &c.Log.Format},
+		{env: "LOG_TIME_FORMAT", key: "log.time_format", value: &c.Log.TimeFormat},
+		{env: "LOG_PATH", key: "log.path", value: &c.Log.Path},
+		{env: "TRACE_EXPORTER", key: "trace.exporter", value: &c.Trace.Exporter},
+		{env: "TRACE_PATH", key: "trace.path", value: &c.Trace.Path},
+		{env: "TRACE_SAMPLE_RATIO", key: "trace.sample_ratio", value: &c.Trace.SampleRatio},
+		{env: "ADMIN_TOKEN", key: "admin.token", value: &c.Admin.Token, secret: true},
+	}
+}
+
+// Load returns the default configuration overridden by the file named by
+// CONFIG_FILE, when set, and by the environment variables. The configuration
+// is validated, every invalid setting is reported in the error
+func Load() (*Config, error) {
+	return load(os.LookupEnv)
+}
+
+func load(lookup func(string) (string, bool)) (*Config, error) {
+	c := Default()
+	if path, ok := lookup(EnvFile); ok && path != "" {
+		if err := c.readFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.readEnv(lookup); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// readFile overrides the config with the settings of the YAML or JSON file,
+// the settings missing from the file are kept
+func (c *Config) readFile(path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+	default:
+		return fmt.Errorf("Unsupported config file %s, use a .yaml, .yml or .json file", path)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Unable to read config file: %w", err)
+	}
+	// JSON is valid YAML, the same decoder reads both
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return fmt.Errorf("Invalid config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// readEnv overrides the config with the environment variables that are set
+func (c *Config) readEnv(lookup func(string) (string, bool)) error {
+	var problems []string
+	for _, f := range c.fields() {
+		s, ok := lookup(f.env)
+		if !ok {
+			continue
+		}
+		if err := set(f.value, s); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", f.env, err))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("Invalid environment variables: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// set parses the value of an environment variable into the setting
+func set(value interface{}, s string) error {
+	switch v := value.(type) {
+	case *string:
+		*v = s
+	case *int:
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", s)
+		}
+		*v = i
+	case *float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", s)
+		}
+		*v = f
+	case *time.Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration, like 5s or 1m30s", s)
+		}
+		*v = d
+	case *[]string:
+		*v = nil
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*v = append(*v, item)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported setting type %T", value)
+	}
+	return nil
+}
+
+// Validate checks every setting, the error lists all the invalid ones.
+// The settings are named by environment variable and by path in the config file
+func (c *Config) Validate() error {
+	names := map[string]string{}
+	for _, f := range c.fields() {
+		names[f.env] = fmt.Sprintf("%s (%s)", f.env, f.key)
+	}
+	var problems []string
+	check := func(ok bool, env, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, names[env]+" "+fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.Server.BindAddress != "", "APP_PORT", "is required")
+	for _, d := range []struct {
+		env   string
+		value time.Duration
+	}{
+		{"SERVER_READ_TIMEOUT", c.Server.ReadTimeout},
+		{"SERVER_WRITE_TIMEOUT", c.Server.WriteTimeout},
+		{"SERVER_IDLE_TIMEOUT", c.Server.IdleTimeout},
+		{"SERVER_SHUTDOWN_TIMEOUT", c.Server.ShutdownTimeout},
+		{"READY_TIMEOUT", c.Server.ReadyTimeout},
+		{"RATE_BREAKER_COOL_DOWN", c.Currency.BreakerCoolDown},
+		{"GRPC_HEALTH_INTERVAL", c.Currency.HealthInterval},
+	} {
+		check(d.value > 0, d.env, "must be greater than 0, got %s", d.value)
+	}
+	check(len(c.Server.CORSOrigins) > 0, "CORS_ORIGINS", "needs at least one origin, use * to allow any")
+
+	check(c.Currency.Address != "", "GRPC_PORT", "is required")
+	check(data.IsCurrencyCode(c.Currency.Base), "BASE_CURRENCY", "must be an ISO 4217 code, got %q", c.Currency.Base)
+	check(c.Currency.RateTimeout >= 0, "RATE_TIMEOUT", "can not be negative, got %s", c.Currency.RateTimeout)
+	check(c.Currency.RateMaxAge >= 0, "RATE_MAX_AGE", "can not be negative, got %s", c.Currency.RateMaxAge)
+	_, err := data.ParseStalePolicy(c.Currency.RateStalePolicy)
+	check(err == nil, "RATE_STALE_POLICY", "must be %s, %s or %s, got %q", data.StaleServe, data.StaleRefetch, data.StaleFail, c.Currency.RateStalePolicy)
+	check(c.Currency.BreakerThreshold >= 1, "RATE_BREAKER_THRESHOLD", "must be at least 1, got %d", c.Currency.BreakerThreshold)
+
+	check(c.Store.Type == data.StoreMemory || c.Store.Type == data.StoreFile,
+		"STORE_TYPE", "must be %s or %s, got %q", data.StoreMemory, data.StoreFile, c.Store.Type)
+	check(c.Store.Type != data.StoreFile || c.Store.Path != "", "STORE_PATH", "is required by the file store")
+
+	_, err = logging.ParseLevel(c.Log.Level)
+	check(err == nil, "LOG_LEVEL", "must be trace, debug, info, warn or error, got %q", c.Log.Level)
+	check(c.Log.Format == logging.FormatJSON || c.Log.Format == logging.FormatText,
+		"LOG_FORMAT", "must be %s or %s, got %q", logging.FormatJSON, logging.FormatText, c.Log.Format)
+
+	switch c.Trace.Exporter {
+	case tracing.ExporterNone, tracing.ExporterStdout:
+	case tracing.ExporterFile:
+		check(c.Trace.Path != "", "TRACE_PATH", "is required by the file exporter")
+	default:
+		check(false, "TRACE_EXPORTER", "must be %s, %s or %s, got %q",
+			tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterFile, c.Trace.Exporter)
+	}
+	check(c.Trace.SampleRatio >= 0 && c.Trace.SampleRatio <= 1, "TRACE_SAMPLE_RATIO", "must be between 0 and 1, got %v", c.Trace.SampleRatio)
+
+	if len(problems) > 0 {
+		return fmt.Errorf("Invalid configuration: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// KeyValues returns every setting by environment variable name, ready to be
+// logged as key/value pairs. The secrets that are set are redacted
+func (c *Config) KeyValues() []interface{} {
+	var kv []interface{}
+	for _, f := range c.fields() {
+		var v interface{}
+		switch value := f.value.(type) {
+		case *string:
+			v = *value
+		case *int:
+			v = *value
+		case *float64:
+			v = *value
+		case *time.Duration:
+			v = value.String()
+		case *[]string:
+			v = strings.Join(*value, ",")
+		}
+		if f.secret && v != "" {
+			v = redacted
+		}
+		kv = append(kv, f.env, v)
+	}
+	return kv
+}
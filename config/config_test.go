@@ -0,0 +1,141 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// env returns a lookup reading the variables from the map
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	c, err := load(env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Server.WriteTimeout != 5*time.Second || c.Server.ShutdownTimeout != 30*time.Second {
+		t.Errorf("Expected the default timeouts, got %+v", c.Server)
+	}
+}
+
+func TestLoad_EnvOverridesFile(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+server:
+  write_timeout: 15s
+  cors_origins: [https://a.example.com]
+currency:
+  base: USD
+`)
+	c, err := load(env(map[string]string{
+		EnvFile:                  path,
+		"BASE_CURRENCY":          "EUR",
+		"CORS_ORIGINS":           "https://a.example.com, https://b.example.com",
+		"RATE_BREAKER_THRESHOLD": "3",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Server.WriteTimeout != 15*time.Second {
+		t.Errorf("Expected the write timeout of the file, got %s", c.Server.WriteTimeout)
+	}
+	if c.Currency.Base != "EUR" {
+		t.Errorf("Expected the base currency of the environment, got %s", c.Currency.Base)
+	}
+	if len(c.Server.CORSOrigins) != 2 || c.Server.CORSOrigins[1] != "https://b.example.com" {
+		t.Errorf("Expected the origins of the environment, got %v", c.Server.CORSOrigins)
+	}
+	if c.Currency.BreakerThreshold != 3 {
+		t.Errorf("Expected a breaker threshold of 3, got %d", c.Currency.BreakerThreshold)
+	}
+	// kept from the defaults
+	if c.Server.ReadTimeout != 10*time.Second {
+		t.Errorf("Expected the default read timeout, got %s", c.Server.ReadTimeout)
+	}
+}
+
+func TestLoad_JSONFile(t *testing.T) {
+	path := writeFile(t, "config.json", `{"store": {"type": "file", "path": "/tmp/cars.json"}, "server": {"idle_timeout": "1m"}}`)
+	c, err := load(env(map[string]string{EnvFile: path}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Store.Type != "file" || c.Server.IdleTimeout != time.Minute {
+		t.Errorf("Expected the settings of the JSON file, got %+v %+v", c.Store, c.Server)
+	}
+}
+
+func TestLoad_InvalidFile(t *testing.T) {
+	path := writeFile(t, "config.yaml", "server:\n  write_timeot: 5s\n")
+	if _, err := load(env(map[string]string{EnvFile: path})); err == nil {
+		t.Error("Expected an error for an unknown setting")
+	}
+	path = writeFile(t, "config.toml", "")
+	if _, err := load(env(map[string]string{EnvFile: path})); err == nil {
+		t.Error("Expected an error for an unsupported file")
+	}
+}
+
+func TestLoad_InvalidSettings(t *testing.T) {
+	_, err := load(env(map[string]string{
+		"SERVER_WRITE_TIMEOUT": "-1s",
+		"BASE_CURRENCY":        "real",
+		"LOG_FORMAT":           "xml",
+	}))
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	// every invalid setting is reported
+	for _, name := range []string{"SERVER_WRITE_TIMEOUT (server.write_timeout)", "BASE_CURRENCY", "LOG_FORMAT"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("Expected %s in the error, got %s", name, err)
+		}
+	}
+
+	// the branches price their cars in currencies the currency server can not convert
+	if c, err := load(env(map[string]string{"BASE_CURRENCY": "CLP"})); err != nil || c.Currency.Base != "CLP" {
+		t.Errorf("Expected CLP as the base currency, got %v", err)
+	}
+
+	if _, err := load(env(map[string]string{"READY_TIMEOUT": "5"})); err == nil || !strings.Contains(err.Error(), "READY_TIMEOUT") {
+		t.Errorf("Expected an error for a duration without unit, got %v", err)
+	}
+}
+
+func TestKeyValues_RedactsSecrets(t *testing.T) {
+	c := Default()
+	c.Admin.Token = "s3cret"
+
+	kv := c.KeyValues()
+	values := map[interface{}]interface{}{}
+	for i := 0; i < len(kv); i += 2 {
+		values[kv[i]] = kv[i+1]
+	}
+	if values["ADMIN_TOKEN"] != redacted {
+		t.Errorf("Expected the admin token to be redacted, got %v", values["ADMIN_TOKEN"])
+	}
+	if values["SERVER_WRITE_TIMEOUT"] != "5s" {
+		t.Errorf("Expected the write timeout, got %v", values["SERVER_WRITE_TIMEOUT"])
+	}
+}
@@ -3,16 +3,29 @@ package data
 import (
 	"context"
 	"fmt"
+	"github.com/CassioRoos/MicroseService/logging"
 	"github.com/CassioRoos/grpc_currency/protos/currency"
 	"github.com/hashicorp/go-hclog"
+	"go.opentelemetry.io/otel/label"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
-	"strings"
+	"sync"
+	"time"
 )
 
 // Is an error raised when a car is not found
 var ErrCarNotFound = fmt.Errorf("Car not found")
 
+// Is an error raised when the currency server does not answer within the deadline
+var ErrRateTimeout = fmt.Errorf("Currency server did not answer in time")
+
+// Is an error raised when the currency server can not be reached
+var ErrCurrencyUnavailable = fmt.Errorf("Currency server unavailable")
+
+// Is an error raised when the car was changed by someone else, the
+// version of the stored car is not the expected one
+var ErrVersionMismatch = fmt.Errorf("Car has been modified, version mismatch")
+
 // Car defines the structure for an API car
 // swagger:model
 type (
@@ -21,223 +34,538 @@ type (
 		//
 		// required: false
 		// min: 1
-		ID int `json:"id"`
+		ID int `json:"id" xml:"id" yaml:"id"`
+
+		// the revision of the car, incremented on every update.
+		// It is assigned by the server and returned as the ETag of the car
+		//
+		// required: false
+		// read only: true
+		Version int `json:"version" xml:"version" yaml:"version"`
 
 		// the color of the car
 		//
 		// required: false
-		Color string `json:"color"`
+		Color string `json:"color" xml:"color" yaml:"color"`
 		// the name of the car
 		//
 		// required: true
 		// max length: 255
-		Name string `json:"name" validate:"required"`
+		Name string `json:"name" xml:"name" yaml:"name" validate:"required"`
 		// the description for this car
 		//
 		// required: false
 		// max length: 255
-		Description string `json:"description"`
+		Description string `json:"description" xml:"description" yaml:"description"`
 
-		// the price for the car
+		// the price for the car, the service base currency is used when
+		// the price has no currency
 		//
 		// required: true
-		// min: 0.01
-		Price float64 `json:"price" validate:"required,gt=0"`
+		Price Money `json:"price" xml:"price" yaml:"price" validate:"required,gt=0"`
 
 		// the license plate for this car
 		//
 		// required: true
 		// pattern: [A-Z]{3}-[0-9]{4}
-		LicensePlate string `json:"license_plate" validate:"required,lcplt"`
+		LicensePlate string `json:"license_plate" xml:"license_plate" yaml:"license_plate" validate:"required,lcplt"`
+
+		// the rate used to convert the price, only set when a currency is requested
+		//
+		// required: false
+		// read only: true
+		Conversion *Conversion `json:"conversion,omitempty" xml:"conversion,omitempty" yaml:"conversion,omitempty"`
+
+		// the price in every requested currency, only set when more than one currency is requested
+		//
+		// required: false
+		// read only: true
+		Prices Prices `json:"prices,omitempty" xml:"prices,omitempty" yaml:"prices,omitempty"`
+	}
+
+	// Conversion describes the rate used to convert the price of a car
+	Conversion struct {
+		// the converted price, only set in the prices of a car
+		Price *Money `json:"price,omitempty" xml:"price,omitempty" yaml:"price,omitempty"`
+		// the currency of the stored price
+		From string `json:"from" xml:"from" yaml:"from"`
+		// the currency of the converted price
+		Currency string `json:"currency" xml:"currency" yaml:"currency"`
+		// the rate applied to the price
+		Rate float64 `json:"rate" xml:"rate" yaml:"rate"`
+		// when the rate was received from the currency server
+		UpdatedAt time.Time `json:"updated_at" xml:"updated_at" yaml:"updated_at"`
+		// the rate is older than the max age configured for the rates
+		Stale bool `json:"stale" xml:"stale" yaml:"stale"`
 	}
 	// This type is to help structure the code, make some changes more independent
 	Cars []*Car
 
 	CarsRepositoryInterface interface {
-		GetCars(cur string) (Cars, error)
-		GetCarById(id int, cur string) (*Car, error)
-		UpdateCar(car Car) error
-		DeleteCar(id int) error
-		AddCar(car *Car)
+		GetCars(ctx context.Context, q CarQuery, currencies []string) (Cars, int, error)
+		GetCarById(ctx context.Context, id int, currencies []string) (*Car, error)
+		UpdateCar(ctx context.Context, car Car, version int) (*Car, error)
+		DeleteCar(ctx context.Context, id int, version int) error
+		AddCar(ctx context.Context, car *Car) (*Car, error)
+		AddCars(ctx context.Context, cars Cars) (Cars, error)
+		ExportCars(ctx context.Context, fn func(*Car) error) error
+		RateStreamState() (StreamState, time.Time)
+		Ping(ctx context.Context) error
 	}
 
 	CarsRepository struct {
 		currency currency.CurrencyClient
+		store    CarStore
 		log      hclog.Logger
-		// simple case
-		rates map[string]float64
-		// GRPC client
-		rateClient currency.Currency_SubscribeRatesClient
+		// currency of the cars stored without one
+		base string
+		// deadline of every call to the currency server, 0 means no deadline
+		timeout time.Duration
+		// rates received from the currency server
+		rates *RateCache
+		// GRPC stream pushing rate updates into the cache
+		stream *RateStream
 	}
 )
 
-func NewCarsRepository(c currency.CurrencyClient, l hclog.Logger) CarsRepositoryInterface {
-	cr := &CarsRepository{c, l, make(map[string]float64), nil}
-	go cr.handleUpdates()
+// NewCarsRepository creates the repository, base is the currency of the
+// prices of the cars stored without a currency and timeout the deadline
+// of every call to the currency server
+func NewCarsRepository(c currency.CurrencyClient, s CarStore, rc *RateCache, base string, timeout time.Duration, l hclog.Logger) CarsRepositoryInterface {
+	cr := &CarsRepository{currency: c, store: s, log: l, base: base, timeout: timeout, rates: rc}
+	cr.stream = NewRateStream(c, l, cr.handleUpdate)
+	go cr.stream.Run(context.Background())
 	return cr
 }
 
 // Responsible to handle the updates and update cache
-func (c *CarsRepository) handleUpdates() {
-	sub, err := c.currency.SubscribeRates(context.Background())
-	if err != nil {
-		c.log.Error("Unable to subscribe for rates", "error", err)
-		return
-	}
-	c.rateClient = sub
-	// receive is blocking
-	for {
-		rrStream, err := sub.Recv()
-		if grpcError := rrStream.GetError(); grpcError != nil {
-			c.log.Error("Error subscribing for rates", "error", grpcError.Message)
-		}
-		if resp := rrStream.GetRateResponse(); resp != nil {
-			if err != nil {
-				c.log.Error("Error receiving message", "error", err)
-				return
-			}
-			c.log.Info("Update received", "destination", resp.Destination.String(), "rate", resp.Rate)
-			c.rates[resp.Destination.String()] = resp.Rate
-		}
-	}
+func (c *CarsRepository) handleUpdate(resp *currency.RateResponse) {
+	rateUpdates.WithLabelValues(resp.Base.String(), resp.Destination.String()).Inc()
+	c.rates.Set(resp.Base.String(), resp.Destination.String(), resp.Rate)
+}
+
+// RateStreamState returns the state of the rate update stream and since when
+// it is in this state. While the stream is not connected cached rates may be stale
+func (c *CarsRepository) RateStreamState() (StreamState, time.Time) {
+	return c.stream.State()
+}
+
+// logger returns the logger of the request in the context, tagged with
+// the request ID, or the logger of the repository
+func (c *CarsRepository) logger(ctx context.Context) hclog.Logger {
+	return logging.FromContext(ctx, c.log)
+}
 
+// Ping reports whether the store of the cars is reachable
+func (c *CarsRepository) Ping(ctx context.Context) error {
+	return c.store.Ping()
 }
 
-// return all the cars in the DB
-func (c *CarsRepository) GetCars(cur string) (Cars, error) {
-	if strings.TrimSpace(cur) == "" {
-		return carList, nil
+// return the page of cars in the DB matching the query and the
+// total of cars matching the filters. The prices are converted to the
+// currencies, see convertAll
+func (c *CarsRepository) GetCars(ctx context.Context, q CarQuery, currencies []string) (_ Cars, _ int, err error) {
+	ctx, span := startSpan(ctx, "CarsRepository.GetCars", label.Array("currencies", currencies))
+	defer endSpan(ctx, span, &err)
+
+	if err := q.Validate(); err != nil {
+		return nil, 0, err
+	}
+	if q.ComparesPrices() {
+		if q.Rates, err = c.baseRates(ctx); err != nil {
+			return nil, 0, err
+		}
+	}
+	cars, total, err := c.store.Find(q)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, car := range cars {
+		c.normalize(car)
+	}
+	if err := c.convertAll(ctx, cars, currencies); err != nil {
+		return nil, 0, err
 	}
+	return cars, total, nil
+}
+
+// return a specific car by the given ID, the price is
+// converted to the currencies, see convertAll
+func (c *CarsRepository) GetCarById(ctx context.Context, id int, currencies []string) (_ *Car, err error) {
+	ctx, span := startSpan(ctx, "CarsRepository.GetCarById", label.Int("car.id", id), label.Array("currencies", currencies))
+	defer endSpan(ctx, span, &err)
 
-	rate, err := c.getRate(cur)
+	car, err := c.store.Get(id)
 	if err != nil {
-		c.log.Error("Unable to get rate", "Currency", cur, err)
 		return nil, err
 	}
-	cr := Cars{}
-	for _, car := range carList {
-		nc := *car
-		nc.Price *= rate
-		cr = append(cr, &nc)
-
+	c.normalize(car)
+	if err := c.convertAll(ctx, Cars{car}, currencies); err != nil {
+		return nil, err
 	}
-	return cr, nil
+	return car, nil
 }
 
-// return a specific car by the given ID
-func (c *CarsRepository) GetCarById(id int, cur string) (*Car, error) {
-	i := c.FindIndexyCarId(id)
-	if id == -1 {
-		return nil, ErrCarNotFound
-	}
-	if strings.TrimSpace(cur) == "" {
-		return carList[i], nil
+// DeleteCar deletes a car from database
+// a version different from 0 must match the stored car version
+func (c *CarsRepository) DeleteCar(ctx context.Context, id int, version int) (err error) {
+	ctx, span := startSpan(ctx, "CarsRepository.DeleteCar", label.Int("car.id", id), label.Int("car.version", version))
+	defer endSpan(ctx, span, &err)
+
+	return c.store.Delete(id, version)
+}
+
+// AddCar adds a new car to DB
+// returns the stored car with the ID assigned by the store
+func (c *CarsRepository) AddCar(ctx context.Context, car *Car) (_ *Car, err error) {
+	ctx, span := startSpan(ctx, "CarsRepository.AddCar")
+	defer endSpan(ctx, span, &err)
+
+	nc := *car
+	if err := c.prepare(&nc); err != nil {
+		return nil, err
 	}
-	rate, err := c.getRate(cur)
-	if err != nil {
-		c.log.Error("Unable to get rate", "Currency", cur, err)
+	if err := c.store.Add(&nc); err != nil {
 		return nil, err
 	}
-	nc := *carList[i]
-	nc.Price *= rate
 	return &nc, nil
+}
+
+// AddCars adds all the cars to DB or none of them
+// returns the stored cars with the IDs assigned by the store
+func (c *CarsRepository) AddCars(ctx context.Context, cars Cars) (_ Cars, err error) {
+	ctx, span := startSpan(ctx, "CarsRepository.AddCars", label.Int("cars", len(cars)))
+	defer endSpan(ctx, span, &err)
 
+	nc := copyCars(cars)
+	for i, car := range nc {
+		if err := c.prepare(car); err != nil {
+			return nil, fmt.Errorf("Car %d: %w", i, err)
+		}
+	}
+	if err := c.store.AddAll(nc); err != nil {
+		return nil, err
+	}
+	return nc, nil
 }
 
-// Finds the index of a car in the Database
-// returns -1 when no car can be found
-func (c *CarsRepository) FindIndexyCarId(id int) int {
-	for i, car := range carList {
-		if car.ID == id {
-			return i
+// ExportCars calls fn for every car in the DB ordered by ID,
+// the export stops at the first error returned by fn or when
+// the context is done, like when the client goes away
+func (c *CarsRepository) ExportCars(ctx context.Context, fn func(*Car) error) (err error) {
+	ctx, span := startSpan(ctx, "CarsRepository.ExportCars")
+	defer endSpan(ctx, span, &err)
+
+	cars, err := c.store.All()
+	if err != nil {
+		return err
+	}
+	for _, car := range cars {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		c.normalize(car)
+		if err := fn(car); err != nil {
+			return err
 		}
 	}
-	return -1
+	return nil
 }
 
-// DeleteCar deletes a car from database
-func (c *CarsRepository) DeleteCar(id int) error {
-	i := c.FindIndexyCarId(id)
-	if i == -1 {
-		return ErrCarNotFound
+// Update a car by the given ID.
+// If a car does not exist by the given id an error is returned
+// CarNotFound error. A version different from 0 must match the stored
+// car version otherwise VersionMismatch error is returned.
+// returns the stored car with the new version
+func (c *CarsRepository) UpdateCar(ctx context.Context, car Car, version int) (_ *Car, err error) {
+	ctx, span := startSpan(ctx, "CarsRepository.UpdateCar", label.Int("car.id", car.ID), label.Int("car.version", version))
+	defer endSpan(ctx, span, &err)
+
+	if err := c.prepare(&car); err != nil {
+		return nil, err
 	}
-	carList = append(carList[:i], carList[i+1:]...)
+	if err := c.store.Update(&car, version); err != nil {
+		return nil, err
+	}
+	return &car, nil
+}
+
+// prepare normalizes a car sent by a client before it is stored, a price
+// without currency is set in the base currency only when its decimal places
+// fit the base currency, it is never rounded
+func (c *CarsRepository) prepare(car *Car) error {
+	price, err := car.Price.WithCurrency(c.base)
+	if err != nil {
+		return err
+	}
+	car.Price = price
+	c.normalize(car)
 	return nil
 }
 
-// AddCar adds a new car to DB
-func (c *CarsRepository) AddCar(car *Car) {
-	car.ID = carList[len(carList)-1].ID + 1
-	carList = append(carList, car)
+// normalize sets the base currency on cars stored without a currency,
+// the amount is rounded to the minor unit of the base currency.
+// The conversion and the prices are set by the repository on read, the ones
+// sent by a client are dropped so they are never stored nor returned by later reads
+func (c *CarsRepository) normalize(car *Car) {
+	if car.Price.Currency == "" {
+		car.Price = car.Price.Convert(1, c.base)
+	}
+	car.Conversion = nil
+	car.Prices = nil
 }
 
-// Update a car by the given ID.
-// If a car does not exist by the given id an error is returned
-// CarNotFound error
-func (c *CarsRepository) UpdateCar(car Car) error {
-	pos := c.FindIndexyCarId(car.ID)
-	if pos == -1 {
-		return ErrCarNotFound
-	}
-	// update the car in the DB
-	carList[pos] = &car
+// convertAll converts the prices of the cars. With a single currency the
+// price is replaced by the converted one, with more currencies the price is
+// kept and the converted prices are set in the prices of the car
+func (c *CarsRepository) convertAll(ctx context.Context, cars Cars, currencies []string) error {
+	currencies = distinct(currencies)
+	if len(currencies) == 0 {
+		return nil
+	}
+	bases := []string{}
+	for _, car := range cars {
+		bases = append(bases, car.Price.Currency)
+	}
+	rates, err := c.getRates(ctx, distinct(bases), currencies)
+	if err != nil {
+		c.logger(ctx).Error("Unable to get rates", "currencies", currencies, "error", err)
+		return err
+	}
+
+	for _, car := range cars {
+		if len(currencies) == 1 {
+			convert(car, rates[rateKey(car.Price.Currency, currencies[0])])
+			continue
+		}
+		car.Prices = Prices{}
+		for _, cur := range currencies {
+			rate := rates[rateKey(car.Price.Currency, cur)]
+			price := car.Price.Convert(rate.Value, rate.Destination)
+			cv := newConversion(rate)
+			cv.Price = &price
+			car.Prices[cur] = cv
+		}
+	}
 	return nil
 }
 
-func (c *CarsRepository) getRate(destination string) (float64, error) {
+// convert applies the rate to the price of the car, the price is
+// rounded to the minor unit of the destination currency
+func convert(car *Car, rate Rate) {
+	car.Price = car.Price.Convert(rate.Value, rate.Destination)
+	car.Conversion = newConversion(rate)
+}
+
+func newConversion(rate Rate) *Conversion {
+	return &Conversion{
+		From:      rate.Base,
+		Currency:  rate.Destination,
+		Rate:      rate.Value,
+		UpdatedAt: rate.UpdatedAt,
+		Stale:     rate.Stale,
+	}
+}
+
+// baseRates returns the rate from the currency of every stored car to the
+// base currency, so the price filters and the price sort of a query compare
+// the prices in a single currency
+func (c *CarsRepository) baseRates(ctx context.Context) (map[string]float64, error) {
+	cars, err := c.store.All()
+	if err != nil {
+		return nil, err
+	}
+	currencies := []string{}
+	for _, car := range cars {
+		if car.Price.Currency != "" {
+			currencies = append(currencies, car.Price.Currency)
+		}
+	}
+	rates, err := c.getRates(ctx, distinct(currencies), []string{c.base})
+	if err != nil {
+		c.logger(ctx).Error("Unable to get the rates to the base currency", "base", c.base, "error", err)
+		return nil, err
+	}
+	br := map[string]float64{}
+	for _, cur := range currencies {
+		br[cur] = rates[rateKey(cur, c.base)].Value
+	}
+	return br, nil
+}
+
+// getRates returns the rates from every base to every destination currency
+// keyed by rateKey. The rates are fetched in parallel so the rates missing
+// from the cache cost a single round trip to the currency server
+func (c *CarsRepository) getRates(ctx context.Context, bases, destinations []string) (map[string]Rate, error) {
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		rates = map[string]Rate{}
+		err   error
+	)
+	for _, base := range bases {
+		for _, destination := range destinations {
+			wg.Add(1)
+			go func(base, destination string) {
+				defer wg.Done()
+				rate, rerr := c.getRate(ctx, base, destination)
+
+				mu.Lock()
+				defer mu.Unlock()
+				if rerr != nil {
+					if err == nil {
+						err = rerr
+					}
+					return
+				}
+				rates[rateKey(base, destination)] = rate
+			}(base, destination)
+		}
+	}
+	wg.Wait()
+	return rates, err
+}
+
+// distinct returns the values without duplicates keeping their order
+func distinct(values []string) []string {
+	seen := map[string]bool{}
+	d := []string{}
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			d = append(d, v)
+		}
+	}
+	return d
+}
+
+// getRate returns the rate from the cache, when the rate is not cached
+// or it is stale and the policy asks to refetch it, it is fetched from
+// the currency server within the deadline of the repository.
+// The rate between a currency and itself is always 1
+func (c *CarsRepository) getRate(ctx context.Context, base, destination string) (_ Rate, err error) {
+	ctx, span := startSpan(ctx, "CarsRepository.getRate", label.String("currency.base", base), label.String("currency.destination", destination))
+	defer endSpan(ctx, span, &err)
+
+	if base == destination {
+		return Rate{Base: base, Destination: destination, Value: 1, UpdatedAt: time.Now()}, nil
+	}
+	// cars can be priced in currencies the server does not convert, like ARS,
+	// the currency was not sent by the client so it is not a bad request
+	if !IsSupportedCurrency(base) {
+		return Rate{}, fmt.Errorf("Unable to convert the price stored in %q, the currency is not supported", base)
+	}
+	if err := ValidateCurrencies(destination); err != nil {
+		return Rate{}, err
+	}
 	// if cached return
-	if _, ok := c.rates[destination]; ok {
-		return c.rates[destination], nil
+	rate, ok := c.rates.Get(base, destination)
+	result := cacheStale
+	switch {
+	case !ok:
+		result = cacheMiss
+	case !rate.Stale:
+		result = cacheHit
+	}
+	rateCache.WithLabelValues(result).Inc()
+	span.SetAttributes(label.String("cache.result", result))
+	if ok {
+		switch {
+		case !rate.Stale, c.rates.Policy() == StaleServe:
+			return rate, nil
+		case c.rates.Policy() == StaleFail:
+			return rate, ErrStaleRate
+		}
+		c.logger(ctx).Debug("Refetching stale rate", "base", base, "destination", destination, "updated_at", rate.UpdatedAt)
 	}
 	rr := &currency.RateRequest{
-		Base:        currency.Currencies(currency.Currencies_value["BRL"]),
+		Base:        currency.Currencies(currency.Currencies_value[base]),
 		Destination: currency.Currencies(currency.Currencies_value[destination])}
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
 	// get initial rate
-	resp, err := c.currency.GetRate(context.Background(), rr)
+	start := time.Now()
+	resp, err := c.currency.GetRate(ctx, rr)
+	rateDuration.Observe(time.Since(start).Seconds())
+	rateRequests.WithLabelValues(status.Code(err).String()).Inc()
 	if err != nil {
-		if s, ok := status.FromError(err); ok {
-			// I`m sure that will be the first item
-			md := s.Details()[0].(*currency.RateRequest)
-			if s.Code() == codes.InvalidArgument {
-				return -1, fmt.Errorf(
-					"Unable to get rate from currency server, base and destination currencies can not be the same base: %s, destination: %s",
-					md.Base.String(),
-					md.Destination.String())
-			}
-			return -1, fmt.Errorf(
-				"Unable to get rate from currency server, base: %s, destination: %s",
-				md.Base.String(),
-				md.Destination.String())
+		// a stale rate is better than no rate while the server is unavailable,
+		// like when the circuit breaker is open
+		if rate, ok := c.rates.Get(base, destination); ok && status.Code(err) == codes.Unavailable {
+			c.logger(ctx).Warn("Currency server unavailable, serving stale rate", "base", base, "destination", destination, "updated_at", rate.UpdatedAt)
+			span.AddEvent(ctx, "serving stale rate", label.String("error", err.Error()))
+			return rate, nil
 		}
-
+		return Rate{}, rateError(err, rr)
 	}
-	// subscribe for future updates
-	err = c.rateClient.Send(rr)
 	// set the value to cache
-	c.rates[destination] = resp.Rate
-	if err != nil {
-		c.log.Error("Unale to subscribe to rates update", "error", err, "destination", destination)
+	rate = c.rates.Set(base, destination, resp.Rate)
+	// subscribe for future updates, the subscription is sent again
+	// when the stream reconnects
+	if err := c.stream.Subscribe(rr); err != nil {
+		c.logger(ctx).Error("Unable to subscribe to rates update", "error", err, "destination", destination)
 	}
-	if err != nil {
-		c.log.Error("Unable to get the rate from currency", "currency", destination)
-		return 1, err
+	return rate, nil
+}
+
+// rateError describes the error returned by the currency server for the request.
+// The server sends the request as a detail of the status, the details are
+// optional so the request sent is used when they are missing
+func rateError(err error, rr *currency.RateRequest) error {
+	s, ok := status.FromError(err)
+	if !ok {
+		// context errors may be returned without a gRPC status
+		s = status.FromContextError(err)
+		if s.Code() == codes.Unknown {
+			return err
+		}
+	}
+	md := rr
+	for _, d := range s.Details() {
+		if r, ok := d.(*currency.RateRequest); ok {
+			md = r
+			break
+		}
+	}
+	switch s.Code() {
+	case codes.DeadlineExceeded:
+		return fmt.Errorf("%w, base: %s, destination: %s", ErrRateTimeout, md.Base.String(), md.Destination.String())
+	case codes.Canceled:
+		return context.Canceled
+	case codes.Unavailable:
+		return fmt.Errorf("%w, base: %s, destination: %s: %s", ErrCurrencyUnavailable, md.Base.String(), md.Destination.String(), s.Message())
+	case codes.InvalidArgument:
+		return fmt.Errorf(
+			"Unable to get rate from currency server, base and destination currencies can not be the same base: %s, destination: %s",
+			md.Base.String(),
+			md.Destination.String())
 	}
-	return resp.Rate, err
+	return fmt.Errorf(
+		"Unable to get rate from currency server, base: %s, destination: %s: %s",
+		md.Base.String(),
+		md.Destination.String(),
+		s.Message())
 }
 
+// carList is the sample data used to seed the memory store
 var carList = []*Car{
 	&Car{ID: 1,
+		Version:      1,
 		Name:         "Cruze",
 		Color:        "Blue",
 		Description:  "A family car",
-		Price:        12461.85,
+		Price:        Money{Amount: 1246185, Currency: "BRL"},
 		LicensePlate: "IVP-5464",
 	},
 	&Car{ID: 2,
+		Version:      1,
 		Name:         "Celta",
 		Color:        "Red",
 		Description:  "Economic car",
-		Price:        837.37,
+		Price:        Money{Amount: 83737, Currency: "BRL"},
 		LicensePlate: "ABC-4321",
 	},
 }
@@ -1,16 +1,373 @@
 package data
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CassioRoos/grpc_currency/protos/currency"
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
 
 func TestCar_Validate(t *testing.T) {
 	c := &Car{
 		Name:         "A",
-		Price:        1.00,
+		Price:        Money{Amount: 100},
 		LicensePlate: "AVX-9999",
 	}
 
-	if err := c.Validate(); err != nil {
+	errs, err := NewValidation().Validate(c)
+	if err != nil || len(errs) != 0 {
+		t.Fatal(err, errs)
+	}
+
+}
+
+func TestCar_ValidateReportsJSONFields(t *testing.T) {
+	c := &Car{Name: "A", Price: Money{Amount: -100}, LicensePlate: "AVX-9999"}
+
+	errs, err := NewValidation().Validate(c)
+	if err != nil || len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", errs)
+	}
+	if errs[0].Field() != "price" || errs[0].Tag() != "gt" || errs[0].Param() != "0" {
+		t.Fatalf("unexpected error %s %s %s", errs[0].Field(), errs[0].Tag(), errs[0].Param())
+	}
+	// the value is the decimal amount the client sent, not the minor units
+	if errs[0].Value() != "-1.00" {
+		t.Fatalf("expected the value -1.00, got %v", errs[0].Value())
+	}
+}
+
+func TestCar_ValidateNil(t *testing.T) {
+	var c *Car
+	if _, err := NewValidation().Validate(c); err == nil {
+		t.Fatal("expected an error for a nil car")
+	}
+}
+
+func TestCar_ValidateCurrency(t *testing.T) {
+	v := NewValidation()
+	for cur, valid := range map[string]bool{"": true, "ARS": true, "BRL": true, "usd": false, "EURO": false} {
+		c := &Car{Name: "A", Price: Money{Amount: 100, Currency: cur}, LicensePlate: "AVX-9999"}
+		if errs, _ := v.Validate(c); (len(errs) == 0) != valid {
+			t.Fatalf("currency %q: expected valid %v, got %v", cur, valid, errs)
+		}
+	}
+}
+
+func TestCarsRepository_RejectsAmountsBeyondBaseCurrency(t *testing.T) {
+	fc := &fakeCurrency{rate: 2, updates: make(chan *currency.StreamingRateResponse)}
+	repo := NewCarsRepository(fc, NewMemoryStore(Cars{}), NewRateCache(time.Minute, StaleRefetch), "JPY", time.Second, hclog.NewNullLogger())
+
+	// a price without currency is in the base currency
+	car := &Car{Name: "Cruze", Price: Money{Amount: 1050}, LicensePlate: "IVP-5464"}
+	if _, err := repo.AddCar(context.Background(), car); !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("expected ErrInvalidAmount for 10.50 JPY, got %v", err)
+	}
+	if _, err := repo.AddCars(context.Background(), Cars{car}); !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("expected ErrInvalidAmount for 10.50 JPY, got %v", err)
+	}
+
+	car.Price = Money{Amount: 1000}
+	added, err := repo.AddCar(context.Background(), car)
+	if err != nil || added.Price != (Money{10, "JPY"}) {
+		t.Fatalf("expected 10 JPY, got %v %v", added, err)
+	}
+	added.Price = Money{Amount: 1050}
+	if _, err := repo.UpdateCar(context.Background(), *added, 0); !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("expected ErrInvalidAmount for 10.50 JPY, got %v", err)
+	}
+}
+
+func TestCarsRepository_StoredUnsupportedCurrency(t *testing.T) {
+	fc := &fakeCurrency{rate: 2, updates: make(chan *currency.StreamingRateResponse)}
+	store := NewMemoryStore(Cars{{ID: 1, Name: "Cruze", Price: Money{Amount: 1000, Currency: "ARS"}, LicensePlate: "IVP-5464"}})
+	repo := NewCarsRepository(fc, store, NewRateCache(time.Minute, StaleRefetch), "EUR", time.Second, hclog.NewNullLogger())
+
+	// the client asked for a supported currency, the error is not a bad request
+	_, _, err := repo.GetCars(context.Background(), CarQuery{}, []string{"USD"})
+	if err == nil || errors.Is(err, ErrUnsupportedCurrency) {
+		t.Fatalf("expected an error not caused by the client, got %v", err)
+	}
+}
+
+func TestCarsRepository_ComparesPricesInBaseCurrency(t *testing.T) {
+	fc := &fakeCurrency{rate: 2, updates: make(chan *currency.StreamingRateResponse)}
+	store := NewMemoryStore(Cars{
+		{ID: 1, Name: "Cruze", Price: Money{Amount: 1000, Currency: "BRL"}, LicensePlate: "IVP-5464"},
+		{ID: 2, Name: "Mustang", Price: Money{Amount: 1500, Currency: "EUR"}, LicensePlate: "ABC-4321"},
+		{ID: 3, Name: "Celta", Price: Money{Amount: 1000, Currency: "JPY"}, LicensePlate: "ABC-1234"},
+	})
+	repo := NewCarsRepository(fc, store, NewRateCache(time.Minute, StaleRefetch), "EUR", time.Second, hclog.NewNullLogger())
+
+	// 10 BRL is 20 EUR and 1000 JPY is 2000 EUR
+	cars, total, err := repo.GetCars(context.Background(), CarQuery{MaxPrice: 100, Sort: "-price"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || cars[0].ID != 1 || cars[1].ID != 2 {
+		t.Fatalf("expected cars 1 and 2, got %v", cars)
+	}
+}
+
+func TestCarsRepository_ConvertsFromCarCurrency(t *testing.T) {
+	fc := &fakeCurrency{rate: 2, updates: make(chan *currency.StreamingRateResponse)}
+	store := NewMemoryStore(Cars{
+		{ID: 1, Name: "Cruze", Price: Money{Amount: 1000, Currency: "BRL"}, LicensePlate: "IVP-5464"},
+		{ID: 2, Name: "Mustang", Price: Money{Amount: 1000, Currency: "USD"}, LicensePlate: "ABC-4321"},
+		{ID: 3, Name: "Celta", Price: Money{Amount: 1000}, LicensePlate: "ABC-1234"},
+	})
+	repo := NewCarsRepository(fc, store, NewRateCache(time.Minute, StaleRefetch), "EUR", time.Second, hclog.NewNullLogger())
+
+	cars, _, err := repo.GetCars(context.Background(), CarQuery{}, []string{"USD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[int]struct {
+		price Money
+		from  string
+	}{1: {Money{2000, "USD"}, "BRL"}, 2: {Money{1000, "USD"}, "USD"}, 3: {Money{2000, "USD"}, "EUR"}}
+	for _, car := range cars {
+		e := expected[car.ID]
+		if car.Price != e.price || car.Conversion.From != e.from {
+			t.Fatalf("car %d: expected %v %s, got %v %+v", car.ID, e.price, e.from, car.Price, car.Conversion)
+		}
+	}
+
+	if _, err := repo.GetCarById(context.Background(), 1, []string{"ARS"}); !errors.Is(err, ErrUnsupportedCurrency) {
+		t.Fatalf("expected ErrUnsupportedCurrency, got %v", err)
+	}
+}
+
+func TestCarsRepository_DropsReadOnlyFields(t *testing.T) {
+	fc := &fakeCurrency{rate: 2, updates: make(chan *currency.StreamingRateResponse)}
+	repo := NewCarsRepository(fc, NewMemoryStore(Cars{}), NewRateCache(time.Minute, StaleRefetch), DefaultCurrency, time.Second, hclog.NewNullLogger())
+	ctx := context.Background()
+	sent := func() *Car {
+		return &Car{
+			Name:         "Cruze",
+			Price:        Money{Amount: 1000, Currency: "BRL"},
+			LicensePlate: "IVP-5464",
+			Conversion:   &Conversion{Currency: "USD", Rate: 99, Stale: true},
+			Prices:       Prices{"USD": &Conversion{Currency: "USD", Rate: 99, Stale: true}},
+		}
+	}
+
+	added, err := repo.AddCar(ctx, sent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch, err := repo.AddCars(ctx, Cars{sent()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	update := sent()
+	update.ID = added.ID
+	updated, err := repo.UpdateCar(ctx, *update, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, car := range []*Car{added, batch[0], updated} {
+		if car.Conversion != nil || car.Prices != nil {
+			t.Fatalf("car %d: expected no conversion, got %+v", car.ID, car)
+		}
+		stored, err := repo.GetCarById(ctx, car.ID, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Conversion != nil || stored.Prices != nil {
+			t.Fatalf("car %d: expected no stored conversion, got %+v", car.ID, stored)
+		}
+	}
+}
+
+func TestCarsRepository_PricesInManyCurrencies(t *testing.T) {
+	fc := &fakeCurrency{rate: 2, updates: make(chan *currency.StreamingRateResponse)}
+	repo := NewCarsRepository(fc, NewMemoryStore(carList), NewRateCache(time.Minute, StaleRefetch), DefaultCurrency, time.Second, hclog.NewNullLogger())
+
+	cars, _, err := repo.GetCars(context.Background(), CarQuery{}, []string{"USD", "EUR", "GBP", "USD"})
+	if err != nil {
 		t.Fatal(err)
 	}
+	for i, car := range cars {
+		if car.Price != carList[i].Price || car.Conversion != nil {
+			t.Fatalf("expected the price to be kept, got %v %+v", car.Price, car.Conversion)
+		}
+		if len(car.Prices) != 3 {
+			t.Fatalf("expected 3 prices, got %v", car.Prices)
+		}
+		for cur, cv := range car.Prices {
+			if *cv.Price != car.Price.Convert(2, cur) || cv.From != "BRL" || cv.Currency != cur {
+				t.Fatalf("unexpected price in %s %v %+v", cur, cv.Price, cv)
+			}
+		}
+	}
+
+	b := &bytes.Buffer{}
+	if err := (XMLEncoder{}).Encode(cars[0], b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "<prices><price><price><amount>") {
+		t.Fatalf("unexpected XML %s", b)
+	}
+}
+
+// slowCurrency is a currency client that never answers
+type slowCurrency struct {
+	fakeCurrency
+}
+
+func (s *slowCurrency) GetRate(ctx context.Context, in *currency.RateRequest, opts ...grpc.CallOption) (*currency.RateResponse, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestCarsRepository_RateDeadline(t *testing.T) {
+	sc := &slowCurrency{fakeCurrency{updates: make(chan *currency.StreamingRateResponse)}}
+	repo := NewCarsRepository(sc, NewMemoryStore(carList), NewRateCache(time.Minute, StaleRefetch), DefaultCurrency, 10*time.Millisecond, hclog.NewNullLogger())
+
+	if _, err := repo.GetCarById(context.Background(), 1, []string{"USD"}); !errors.Is(err, ErrRateTimeout) {
+		t.Fatalf("expected ErrRateTimeout, got %v", err)
+	}
+
+	// the request is canceled before the deadline of the repository
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := repo.GetCarById(ctx, 1, []string{"USD"}); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+// downCurrency is a currency server that is unavailable
+type downCurrency struct {
+	fakeCurrency
+}
+
+func (d *downCurrency) GetRate(ctx context.Context, in *currency.RateRequest, opts ...grpc.CallOption) (*currency.RateResponse, error) {
+	return nil, status.Error(codes.Unavailable, "Circuit breaker is open")
+}
+
+func TestCarsRepository_ServesStaleRateWhileUnavailable(t *testing.T) {
+	dc := &downCurrency{fakeCurrency{updates: make(chan *currency.StreamingRateResponse)}}
+	rc := NewRateCache(time.Minute, StaleRefetch)
+	repo := NewCarsRepository(dc, NewMemoryStore(carList), rc, DefaultCurrency, time.Second, hclog.NewNullLogger())
+
+	if _, err := repo.GetCarById(context.Background(), 1, []string{"USD"}); !errors.Is(err, ErrCurrencyUnavailable) {
+		t.Fatalf("expected ErrCurrencyUnavailable, got %v", err)
+	}
+
+	rc.Set("BRL", "USD", 3)
+	old := rc.rates[rateKey("BRL", "USD")]
+	old.UpdatedAt = time.Now().Add(-time.Hour)
+	rc.rates[rateKey("BRL", "USD")] = old
+
+	car, err := repo.GetCarById(context.Background(), 1, []string{"USD"})
+	if err != nil || car.Conversion.Rate != 3 || !car.Conversion.Stale {
+		t.Fatalf("expected the stale rate, got %+v %v", car.Conversion, err)
+	}
+}
+
+// fakeCurrency is a currency client returning a fixed rate, the
+// subscription pushes the updates written into the updates channel
+type fakeCurrency struct {
+	rate    float64
+	updates chan *currency.StreamingRateResponse
+}
+
+func (f *fakeCurrency) GetRate(ctx context.Context, in *currency.RateRequest, opts ...grpc.CallOption) (*currency.RateResponse, error) {
+	return &currency.RateResponse{Base: in.Base, Destination: in.Destination, Rate: f.rate}, nil
+}
+
+func (f *fakeCurrency) SubscribeRates(ctx context.Context, opts ...grpc.CallOption) (currency.Currency_SubscribeRatesClient, error) {
+	return &fakeStream{updates: f.updates}, nil
+}
+
+type fakeStream struct {
+	grpc.ClientStream
+	updates chan *currency.StreamingRateResponse
+}
+
+func (s *fakeStream) Send(*currency.RateRequest) error {
+	return nil
+}
+
+func (s *fakeStream) Recv() (*currency.StreamingRateResponse, error) {
+	r, ok := <-s.updates
+	if !ok {
+		// the stream never ends during the tests
+		select {}
+	}
+	return r, nil
+}
+
+func TestCarsRepository_ConcurrentAccess(t *testing.T) {
+	fc := &fakeCurrency{rate: 2, updates: make(chan *currency.StreamingRateResponse)}
+	repo := NewCarsRepository(fc, NewMemoryStore(carList), NewRateCache(time.Minute, StaleRefetch), DefaultCurrency, time.Second, hclog.NewNullLogger())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(fc.updates)
+		for {
+			update := &currency.StreamingRateResponse{
+				Message: &currency.StreamingRateResponse_RateResponse{
+					RateResponse: &currency.RateResponse{Destination: currency.Currencies_USD, Rate: 3},
+				},
+			}
+			select {
+			case fc.updates <- update:
+			case <-done:
+				return
+			}
+		}
+	}()
+	defer close(done)
 
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			car, err := repo.AddCar(context.Background(), &Car{Name: "Onix", Price: Money{Amount: 10000}, LicensePlate: "ABC-1234"})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			car.Color = "Black"
+			if car, err = repo.UpdateCar(context.Background(), *car, car.Version); err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := repo.GetCarById(context.Background(), car.ID, []string{"USD"}); err != nil {
+				t.Error(err)
+			}
+			if _, _, err := repo.GetCars(context.Background(), CarQuery{Sort: "-price"}, []string{"USD"}); err != nil {
+				t.Error(err)
+			}
+			if _, _, err := repo.GetCars(context.Background(), CarQuery{Limit: 1}, nil); err != nil {
+				t.Error(err)
+			}
+			if err := repo.DeleteCar(context.Background(), car.ID, car.Version); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	cars, _, err := repo.GetCars(context.Background(), CarQuery{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cars) != len(carList) {
+		t.Fatalf("expected %d cars, got %d", len(carList), len(cars))
+	}
 }
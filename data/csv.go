@@ -0,0 +1,135 @@
+package data
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// csvHeader holds the columns written for every car
+var csvHeader = []string{"id", "version", "name", "color", "description", "price", "currency", "license_plate"}
+
+// CSVWriter writes cars as CSV rows, the header is written before the first car.
+// Every car is flushed to the underlying writer so it can be used to stream cars
+type CSVWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+// NewCSVWriter creates a CSVWriter writing into w
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w)}
+}
+
+// WriteHeader writes the header row, it is only written once
+func (c *CSVWriter) WriteHeader() error {
+	if c.header {
+		return nil
+	}
+	c.header = true
+	if err := c.w.Write(csvHeader); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// Write writes the car as a CSV row
+func (c *CSVWriter) Write(car *Car) error {
+	if err := c.WriteHeader(); err != nil {
+		return err
+	}
+	err := c.w.Write([]string{
+		strconv.Itoa(car.ID),
+		strconv.Itoa(car.Version),
+		car.Name,
+		car.Color,
+		car.Description,
+		car.Price.String(),
+		car.Price.Currency,
+		car.LicensePlate,
+	})
+	if err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// ToCSV serializes the cars to CSV with a header row
+func ToCSV(cars Cars, w io.Writer) error {
+	cw := NewCSVWriter(w)
+	if err := cw.WriteHeader(); err != nil {
+		return err
+	}
+	for _, car := range cars {
+		if err := cw.Write(car); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FromCSV deserializes cars from CSV, the first row is the header naming
+// the columns, unknown columns are ignored
+func FromCSV(r io.Reader) (Cars, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	cars := Cars{}
+	if len(rows) == 0 {
+		return cars, nil
+	}
+
+	header := rows[0]
+	for line, row := range rows[1:] {
+		car := &Car{}
+		// the price is parsed once the currency is known
+		price := ""
+		for i, col := range header {
+			if col == "price" {
+				price = row[i]
+				continue
+			}
+			if err := setCSVField(car, col, row[i]); err != nil {
+				return nil, fmt.Errorf("Invalid %s on line %d: %s", col, line+2, err)
+			}
+		}
+		if price != "" {
+			p, err := ParseMoney(price, car.Price.Currency)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid price on line %d: %s", line+2, err)
+			}
+			car.Price = p
+		}
+		cars = append(cars, car)
+	}
+	return cars, nil
+}
+
+func setCSVField(car *Car, col, value string) error {
+	var err error
+	switch col {
+	case "id":
+		if value != "" {
+			car.ID, err = strconv.Atoi(value)
+		}
+	case "version":
+		if value != "" {
+			car.Version, err = strconv.Atoi(value)
+		}
+	case "name":
+		car.Name = value
+	case "color":
+		car.Color = value
+	case "description":
+		car.Description = value
+	case "currency":
+		car.Price.Currency = value
+	case "license_plate":
+		car.LicensePlate = value
+	}
+	return err
+}
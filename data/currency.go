@@ -0,0 +1,76 @@
+package data
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/CassioRoos/grpc_currency/protos/currency"
+)
+
+// DefaultCurrency is the currency of the prices when no base currency is configured
+const DefaultCurrency = "BRL"
+
+// ErrUnsupportedCurrency is returned when a conversion involves a currency
+// unknown by the currency server, the returned errors wrap it and list
+// the supported currencies
+var ErrUnsupportedCurrency = fmt.Errorf("Currency not supported by the currency server")
+
+// currencyCode matches the format of an ISO 4217 alphabetic code
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// CurrencyInfo describes a currency the currency server can convert
+type CurrencyInfo struct {
+	// the ISO 4217 code of the currency
+	Code string `json:"code"`
+	// the number of decimal places of the amounts in the currency
+	MinorUnits int `json:"minor_units"`
+}
+
+// IsCurrencyCode reports whether the code has the format of an ISO 4217 code.
+// Prices may be stored in any currency, even one the currency server can not convert
+func IsCurrencyCode(code string) bool {
+	return currencyCode.MatchString(code)
+}
+
+// IsSupportedCurrency reports whether the currency server can convert the currency
+func IsSupportedCurrency(code string) bool {
+	_, ok := currency.Currencies_value[code]
+	return ok
+}
+
+// SupportedCurrencies returns the codes of the currencies the currency
+// server can convert, in alphabetical order
+func SupportedCurrencies() []string {
+	codes := []string{}
+	for code := range currency.Currencies_value {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Currencies describes the currencies the currency server can convert
+func Currencies() []CurrencyInfo {
+	ci := []CurrencyInfo{}
+	for _, code := range SupportedCurrencies() {
+		ci = append(ci, CurrencyInfo{Code: code, MinorUnits: MinorUnits(code)})
+	}
+	return ci
+}
+
+// ValidateCurrencies checks that the currency server can convert every currency,
+// the error wraps ErrUnsupportedCurrency
+func ValidateCurrencies(codes ...string) error {
+	for _, code := range codes {
+		if !IsSupportedCurrency(code) {
+			return unsupportedCurrency(code)
+		}
+	}
+	return nil
+}
+
+func unsupportedCurrency(code string) error {
+	return fmt.Errorf("%w: %q, supported currencies are %s", ErrUnsupportedCurrency, code, strings.Join(SupportedCurrencies(), ", "))
+}
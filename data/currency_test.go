@@ -0,0 +1,46 @@
+package data
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/CassioRoos/grpc_currency/protos/currency"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestValidateCurrencies(t *testing.T) {
+	if err := ValidateCurrencies("USD", "EUR", "BRL"); err != nil {
+		t.Fatal(err)
+	}
+	err := ValidateCurrencies("USD", "ARS")
+	if !errors.Is(err, ErrUnsupportedCurrency) {
+		t.Fatalf("expected ErrUnsupportedCurrency, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `"ARS"`) || !strings.Contains(err.Error(), "BRL, CAD") {
+		t.Fatalf("expected the currency and the supported currencies, got %s", err)
+	}
+}
+
+func TestRateError(t *testing.T) {
+	rr := &currency.RateRequest{Base: currency.Currencies_BRL, Destination: currency.Currencies_USD}
+
+	// a status without details must not panic
+	err := rateError(status.Error(codes.Unavailable, "connection refused"), rr)
+	if !strings.Contains(err.Error(), "base: BRL, destination: USD: connection refused") {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	same := &currency.RateRequest{Base: currency.Currencies_USD, Destination: currency.Currencies_USD}
+	s, _ := status.New(codes.InvalidArgument, "same currencies").WithDetails(same)
+	err = rateError(s.Err(), rr)
+	if !strings.Contains(err.Error(), "can not be the same base: USD, destination: USD") {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	plain := errors.New("boom")
+	if err := rateError(plain, rr); err != plain {
+		t.Fatalf("expected the error to be returned as is, got %v", err)
+	}
+}
@@ -0,0 +1,243 @@
+package data
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Encoder serializes and deserializes cars for a media type
+type Encoder interface {
+	// MediaTypes returns the media types handled by the encoder,
+	// the first one is sent as the Content-Type of the responses
+	MediaTypes() []string
+	// Encode serializes i into w
+	Encode(i interface{}, w io.Writer) error
+	// Decode deserializes the content of r into i
+	Decode(i interface{}, r io.Reader) error
+}
+
+// ErrNotAcceptable is returned when no encoder matches the Accept header
+var ErrNotAcceptable = fmt.Errorf("Not acceptable")
+
+// ErrUnsupportedMediaType is returned when no encoder matches the Content-Type header
+var ErrUnsupportedMediaType = fmt.Errorf("Unsupported media type")
+
+// EncoderRegistry selects the encoder for the Accept and Content-Type headers
+type EncoderRegistry struct {
+	encoders []Encoder
+}
+
+// NewEncoderRegistry creates a registry with the given encoders,
+// the first encoder is used when the client accepts anything
+func NewEncoderRegistry(e ...Encoder) *EncoderRegistry {
+	return &EncoderRegistry{encoders: e}
+}
+
+// NewDefaultEncoders creates a registry with the JSON, XML, CSV and YAML encoders
+func NewDefaultEncoders() *EncoderRegistry {
+	return NewEncoderRegistry(JSONEncoder{}, XMLEncoder{}, CSVEncoder{}, YAMLEncoder{})
+}
+
+// Register adds an encoder to the registry
+func (er *EncoderRegistry) Register(e Encoder) {
+	er.encoders = append(er.encoders, e)
+}
+
+// MediaTypes returns the main media type of every encoder
+func (er *EncoderRegistry) MediaTypes() []string {
+	mt := []string{}
+	for _, e := range er.encoders {
+		mt = append(mt, e.MediaTypes()[0])
+	}
+	return mt
+}
+
+// ForContentType returns the encoder for the Content-Type header,
+// a missing header is handled as JSON
+func (er *EncoderRegistry) ForContentType(contentType string) (Encoder, error) {
+	if strings.TrimSpace(contentType) == "" {
+		return er.encoders[0], nil
+	}
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, ErrUnsupportedMediaType
+	}
+	if e := er.find(mt); e != nil {
+		return e, nil
+	}
+	return nil, ErrUnsupportedMediaType
+}
+
+// Negotiate returns the encoder preferred by the Accept header (RFC 7231),
+// a missing header accepts anything
+func (er *EncoderRegistry) Negotiate(accept string) (Encoder, error) {
+	if strings.TrimSpace(accept) == "" {
+		return er.encoders[0], nil
+	}
+
+	type mediaRange struct {
+		mt string
+		q  float64
+	}
+	ranges := []mediaRange{}
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, mediaRange{mt, q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	for _, r := range ranges {
+		switch {
+		case r.mt == "*/*":
+			return er.encoders[0], nil
+		case strings.HasSuffix(r.mt, "/*"):
+			prefix := strings.TrimSuffix(r.mt, "*")
+			for _, e := range er.encoders {
+				for _, mt := range e.MediaTypes() {
+					if strings.HasPrefix(mt, prefix) {
+						return e, nil
+					}
+				}
+			}
+		default:
+			if e := er.find(r.mt); e != nil {
+				return e, nil
+			}
+		}
+	}
+	return nil, ErrNotAcceptable
+}
+
+func (er *EncoderRegistry) find(mediaType string) Encoder {
+	for _, e := range er.encoders {
+		for _, mt := range e.MediaTypes() {
+			if mt == mediaType {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+// JSONEncoder uses ToJSON and FromJSON
+type JSONEncoder struct{}
+
+func (JSONEncoder) MediaTypes() []string { return []string{"application/json"} }
+
+func (JSONEncoder) Encode(i interface{}, w io.Writer) error { return ToJSON(i, w) }
+
+func (JSONEncoder) Decode(i interface{}, r io.Reader) error { return FromJSON(i, r) }
+
+// XMLEncoder serializes a car as <car> and a list of cars as <cars><car>...</car></cars>
+type XMLEncoder struct{}
+
+// carsXML is the XML document for a list of cars
+type carsXML struct {
+	XMLName xml.Name `xml:"cars"`
+	Cars    Cars     `xml:"car"`
+}
+
+func (XMLEncoder) MediaTypes() []string { return []string{"application/xml", "text/xml"} }
+
+func (XMLEncoder) Encode(i interface{}, w io.Writer) error {
+	e := xml.NewEncoder(w)
+	switch v := i.(type) {
+	case Cars:
+		return e.Encode(carsXML{Cars: v})
+	case *Car, Car:
+		return e.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: "car"}})
+	default:
+		return e.Encode(i)
+	}
+}
+
+func (XMLEncoder) Decode(i interface{}, r io.Reader) error {
+	if cars, ok := i.(*Cars); ok {
+		doc := carsXML{}
+		if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+			return err
+		}
+		*cars = doc.Cars
+		return nil
+	}
+	return xml.NewDecoder(r).Decode(i)
+}
+
+// CSVEncoder serializes cars as CSV with a header row, it only handles cars
+type CSVEncoder struct{}
+
+func (CSVEncoder) MediaTypes() []string { return []string{"text/csv"} }
+
+func (CSVEncoder) Encode(i interface{}, w io.Writer) error {
+	switch v := i.(type) {
+	case Cars:
+		return ToCSV(v, w)
+	case *Car:
+		return ToCSV(Cars{v}, w)
+	case Car:
+		return ToCSV(Cars{&v}, w)
+	default:
+		return fmt.Errorf("Unable to encode %T as CSV", i)
+	}
+}
+
+func (CSVEncoder) Decode(i interface{}, r io.Reader) error {
+	cars, err := FromCSV(r)
+	if err != nil {
+		return err
+	}
+	switch v := i.(type) {
+	case *Cars:
+		*v = cars
+	case *Car:
+		if len(cars) != 1 {
+			return fmt.Errorf("Expected a single car, got %d", len(cars))
+		}
+		*v = *cars[0]
+	default:
+		return fmt.Errorf("Unable to decode CSV into %T", i)
+	}
+	return nil
+}
+
+// YAMLEncoder serializes using YAML
+type YAMLEncoder struct{}
+
+func (YAMLEncoder) MediaTypes() []string {
+	return []string{"application/yaml", "application/x-yaml", "text/yaml"}
+}
+
+func (YAMLEncoder) Encode(i interface{}, w io.Writer) error {
+	return yaml.NewEncoder(w).Encode(i)
+}
+
+func (YAMLEncoder) Decode(i interface{}, r io.Reader) error {
+	// the decoder of yaml.v2 does not fail on empty documents
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if len(strings.TrimSpace(string(b))) == 0 {
+		return io.EOF
+	}
+	return yaml.Unmarshal(b, i)
+}
@@ -0,0 +1,53 @@
+package data
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestEncoderRegistry_Negotiate(t *testing.T) {
+	er := NewDefaultEncoders()
+	cases := map[string]string{
+		"":                                      "application/json",
+		"*/*":                                   "application/json",
+		"application/xml":                       "application/xml",
+		"text/csv;q=0.5, application/yaml":      "application/yaml",
+		"image/png, text/csv;q=0.8":             "text/csv",
+		"application/json;q=0, application/xml": "application/xml",
+	}
+	for accept, want := range cases {
+		e, err := er.Negotiate(accept)
+		if err != nil {
+			t.Fatalf("%q: %s", accept, err)
+		}
+		if got := e.MediaTypes()[0]; got != want {
+			t.Fatalf("%q: expected %s, got %s", accept, want, got)
+		}
+	}
+
+	if _, err := er.Negotiate("image/png"); err != ErrNotAcceptable {
+		t.Fatalf("expected ErrNotAcceptable, got %v", err)
+	}
+	if _, err := er.ForContentType("application/msword"); err != ErrUnsupportedMediaType {
+		t.Fatalf("expected ErrUnsupportedMediaType, got %v", err)
+	}
+}
+
+func TestEncoders_RoundTrip(t *testing.T) {
+	car := &Car{ID: 1, Version: 2, Name: "Cruze", Color: "Blue", Description: "A family, car", Price: Money{Amount: 1246185, Currency: "BRL"}, LicensePlate: "IVP-5464"}
+
+	for _, e := range []Encoder{JSONEncoder{}, XMLEncoder{}, CSVEncoder{}, YAMLEncoder{}} {
+		b := &bytes.Buffer{}
+		if err := e.Encode(Cars{car}, b); err != nil {
+			t.Fatalf("%s: %s", e.MediaTypes()[0], err)
+		}
+		cars := Cars{}
+		if err := e.Decode(&cars, b); err != nil {
+			t.Fatalf("%s: %s", e.MediaTypes()[0], err)
+		}
+		if len(cars) != 1 || !reflect.DeepEqual(cars[0], car) {
+			t.Fatalf("%s: expected %v, got %v", e.MediaTypes()[0], car, cars)
+		}
+	}
+}
@@ -1,8 +1,11 @@
 package data
 
 import (
+	"bufio"
 	"encoding/json"
+	"fmt"
 	"io"
+	"unicode"
 )
 
 func FromJSON(i interface{}, r io.Reader) error {
@@ -22,3 +25,63 @@ func ToJSON(i interface{}, w io.Writer) error {
 	// now we have to encode ourselfs, because C is pointing to the slice of cars
 	return e.Encode(i)
 }
+
+// ErrTooManyCars is returned when a stream has more cars than the limit
+var ErrTooManyCars = fmt.Errorf("Too many cars")
+
+// FromJSONStream decodes a list of cars sent either as a JSON array or
+// as newline delimited JSON (http://ndjson.org/), one car per line.
+// The decoding stops as soon as the stream has more than max cars,
+// null cars are rejected
+func FromJSONStream(r io.Reader, max int) (Cars, error) {
+	br := bufio.NewReader(r)
+	// the first character tells if it is an array or a stream of objects
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			if err == io.EOF {
+				return Cars{}, nil
+			}
+			return nil, err
+		}
+		if !unicode.IsSpace(rune(b[0])) {
+			break
+		}
+		br.ReadByte()
+	}
+
+	cars := Cars{}
+	d := json.NewDecoder(br)
+	b, _ := br.Peek(1)
+	array := b[0] == '['
+	if array {
+		// the opening bracket
+		if _, err := d.Token(); err != nil {
+			return nil, err
+		}
+	}
+	for {
+		if array && !d.More() {
+			// the closing bracket
+			if _, err := d.Token(); err != nil {
+				return nil, err
+			}
+			return cars, nil
+		}
+		var car *Car
+		err := d.Decode(&car)
+		if !array && err == io.EOF {
+			return cars, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if car == nil {
+			return nil, fmt.Errorf("Car %d is null", len(cars))
+		}
+		if len(cars) == max {
+			return nil, fmt.Errorf("%w, the limit is %d", ErrTooManyCars, max)
+		}
+		cars = append(cars, car)
+	}
+}
@@ -0,0 +1,46 @@
+package data
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFromJSONStream(t *testing.T) {
+	inputs := []string{
+		` [{"name":"Cruze"},{"name":"Celta"}]`,
+		"{\"name\":\"Cruze\"}\n{\"name\":\"Celta\"}\n",
+	}
+	for _, in := range inputs {
+		cars, err := FromJSONStream(strings.NewReader(in), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cars) != 2 || cars[1].Name != "Celta" {
+			t.Fatalf("unexpected cars %v from %q", cars, in)
+		}
+	}
+
+	if _, err := FromJSONStream(strings.NewReader("{\"name\":"), 10); err == nil {
+		t.Fatal("expected error for a broken stream")
+	}
+	if _, err := FromJSONStream(strings.NewReader(`[{"name":"Cruze"`), 10); err == nil {
+		t.Fatal("expected error for a broken array")
+	}
+	for _, in := range []string{`[null]`, "{\"name\":\"Cruze\"}\nnull\n"} {
+		if _, err := FromJSONStream(strings.NewReader(in), 10); err == nil {
+			t.Fatalf("expected error for a null car in %q", in)
+		}
+	}
+}
+
+func TestFromJSONStream_StopsAtMax(t *testing.T) {
+	for _, in := range []string{
+		`[{"name":"A"},{"name":"B"},{"name":"C"}, this is never read`,
+		"{\"name\":\"A\"}\n{\"name\":\"B\"}\n{\"name\":\"C\"}\nthis is never read",
+	} {
+		if _, err := FromJSONStream(strings.NewReader(in), 2); !errors.Is(err, ErrTooManyCars) {
+			t.Fatalf("expected ErrTooManyCars for %q, got %v", in, err)
+		}
+	}
+}
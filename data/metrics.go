@@ -0,0 +1,36 @@
+package data
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Results of the rate cache lookups
+const (
+	cacheHit   = "hit"
+	cacheMiss  = "miss"
+	cacheStale = "stale"
+)
+
+var (
+	rateRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "currency_rate_requests_total",
+		Help: "Number of GetRate calls to the currency server by gRPC status code",
+	}, []string{"code"})
+
+	rateDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "currency_rate_request_duration_seconds",
+		Help:    "Latency of the GetRate calls to the currency server",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	rateUpdates = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "currency_rate_updates_total",
+		Help: "Number of rate updates received from the rate stream by currency",
+	}, []string{"base", "destination"})
+
+	rateCache = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "currency_rate_cache_total",
+		Help: "Number of rate cache lookups by result, hit, miss or stale",
+	}, []string{"result"})
+)
@@ -0,0 +1,234 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// minorUnits holds the number of decimal places of the currencies whose minor
+// unit is not the cent, as defined by ISO 4217. Every other currency has 2
+var minorUnits = map[string]int{
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0,
+	"KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0,
+	"XOF": 0, "XPF": 0,
+}
+
+// MinorUnits returns the number of decimal places of the currency,
+// amounts in that currency are rounded to it
+func MinorUnits(currency string) int {
+	if d, ok := minorUnits[currency]; ok {
+		return d
+	}
+	return 2
+}
+
+// decimalAmount matches the decimal amounts accepted by ParseMoney
+var decimalAmount = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// Money is an amount in the minor unit of a currency, like cents for BRL,
+// so prices are exact and never suffer from floating point rounding.
+// A Money without currency uses 2 decimal places until a currency is set.
+//
+// Every rounding, like in a conversion, rounds half away from zero to the
+// minor unit of the currency
+//
+// swagger:model
+type Money struct {
+	// the amount in the minor unit of the currency
+	Amount int64
+	// the ISO 4217 code of the currency
+	Currency string
+}
+
+// moneyDoc is the representation of Money in the encoded documents,
+// the amount is a decimal string so clients do not parse it as a float
+type moneyDoc struct {
+	Amount   string `json:"amount" xml:"amount" yaml:"amount"`
+	Currency string `json:"currency,omitempty" xml:"currency,omitempty" yaml:"currency,omitempty"`
+}
+
+// ErrInvalidAmount is returned for amounts that are not a decimal number or
+// that have more decimal places than their currency
+var ErrInvalidAmount = fmt.Errorf("Invalid amount")
+
+// ParseMoney parses a decimal amount like 12461.85 in the currency,
+// amounts with more decimal places than the currency has are rejected
+func ParseMoney(amount, currency string) (Money, error) {
+	amount = strings.TrimSpace(amount)
+	if !decimalAmount.MatchString(amount) {
+		return Money{}, fmt.Errorf("%w %q, expected a decimal number like 10.50", ErrInvalidAmount, amount)
+	}
+	digits := MinorUnits(currency)
+	whole, frac := amount, ""
+	if i := strings.IndexByte(amount, '.'); i >= 0 {
+		whole, frac = amount[:i], amount[i+1:]
+	}
+	if len(frac) > digits {
+		return Money{}, fmt.Errorf("%w %q, amounts in %s have at most %d decimal places", ErrInvalidAmount, amount, currencyName(currency), digits)
+	}
+	frac += strings.Repeat("0", digits-len(frac))
+	minor, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w %q, the amount is too large", ErrInvalidAmount, amount)
+	}
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+// WithCurrency sets the currency of a money without currency, the amount
+// keeps its value in the minor unit of the currency. An amount with more
+// decimal places than the currency has, like 10.50 in JPY, is rejected
+// instead of rounded. A money with a currency is returned as it is
+func (m Money) WithCurrency(currency string) (Money, error) {
+	if m.Currency != "" {
+		return m, nil
+	}
+	digits := MinorUnits(currency)
+	shift := digits - MinorUnits(m.Currency)
+	scale := int64(math.Pow10(abs(shift)))
+	if shift >= 0 {
+		return Money{Amount: m.Amount * scale, Currency: currency}, nil
+	}
+	if m.Amount%scale != 0 {
+		return Money{}, fmt.Errorf("%w %q, amounts in %s have at most %d decimal places", ErrInvalidAmount, m.String(), currency, digits)
+	}
+	return Money{Amount: m.Amount / scale, Currency: currency}, nil
+}
+
+// String returns the amount as a decimal number with the decimal places of the currency
+func (m Money) String() string {
+	digits := MinorUnits(m.Currency)
+	s := strconv.FormatInt(m.Amount, 10)
+	sign := ""
+	if m.Amount < 0 {
+		sign, s = "-", s[1:]
+	}
+	if digits == 0 {
+		return sign + s
+	}
+	if len(s) <= digits {
+		s = strings.Repeat("0", digits-len(s)+1) + s
+	}
+	return sign + s[:len(s)-digits] + "." + s[len(s)-digits:]
+}
+
+// Float64 returns the amount in the major unit of the currency,
+// it is meant for comparisons and must not be used in calculations
+func (m Money) Float64() float64 {
+	return float64(m.Amount) / math.Pow10(MinorUnits(m.Currency))
+}
+
+// Convert returns the amount multiplied by the rate in the destination currency,
+// rounded to the minor unit of the destination currency
+func (m Money) Convert(rate float64, destination string) Money {
+	// the shortest decimal representing the rate, 0.1 is used instead of
+	// the closest binary float 0.1000000000000000055...
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(rate, 'f', -1, 64))
+	v := new(big.Rat).SetInt64(m.Amount)
+	v.Mul(v, r)
+
+	shift := MinorUnits(destination) - MinorUnits(m.Currency)
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(shift))), nil))
+	if shift >= 0 {
+		v.Mul(v, scale)
+	} else {
+		v.Quo(v, scale)
+	}
+	return Money{Amount: roundHalfAwayFromZero(v), Currency: destination}
+}
+
+// roundHalfAwayFromZero rounds the number to the closest integer,
+// halves are rounded away from zero like 0.5 to 1 and -0.5 to -1
+func roundHalfAwayFromZero(v *big.Rat) int64 {
+	q, r := new(big.Int).QuoRem(v.Num(), v.Denom(), new(big.Int))
+	// |r| / den >= 1/2
+	if new(big.Int).Mul(new(big.Int).Abs(r), big.NewInt(2)).Cmp(v.Denom()) >= 0 {
+		q.Add(q, big.NewInt(int64(v.Sign())))
+	}
+	return q.Int64()
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+func currencyName(currency string) string {
+	if currency == "" {
+		return "the base currency"
+	}
+	return currency
+}
+
+func (m Money) doc() moneyDoc {
+	return moneyDoc{Amount: m.String(), Currency: m.Currency}
+}
+
+func (m *Money) fromDoc(d moneyDoc) error {
+	v, err := ParseMoney(d.Amount, d.Currency)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// MarshalJSON encodes the money as {"amount": "12461.85", "currency": "BRL"}
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.doc())
+}
+
+// UnmarshalJSON decodes the money object, a bare number is accepted
+// as an amount without currency, as stored before prices had a currency
+func (m *Money) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] != '{' {
+		var n json.Number
+		if err := json.Unmarshal(b, &n); err != nil {
+			return err
+		}
+		return m.fromDoc(moneyDoc{Amount: n.String()})
+	}
+	d := moneyDoc{}
+	if err := json.Unmarshal(b, &d); err != nil {
+		return err
+	}
+	return m.fromDoc(d)
+}
+
+// MarshalXML encodes the money as <amount> and <currency> elements
+func (m Money) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(m.doc(), start)
+}
+
+// UnmarshalXML decodes the <amount> and <currency> elements
+func (m *Money) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	doc := moneyDoc{}
+	if err := d.DecodeElement(&doc, &start); err != nil {
+		return err
+	}
+	return m.fromDoc(doc)
+}
+
+// MarshalYAML encodes the money as a mapping with amount and currency
+func (m Money) MarshalYAML() (interface{}, error) {
+	return m.doc(), nil
+}
+
+// UnmarshalYAML decodes the mapping with amount and currency
+func (m *Money) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	doc := moneyDoc{}
+	if err := unmarshal(&doc); err != nil {
+		return err
+	}
+	return m.fromDoc(doc)
+}
@@ -0,0 +1,114 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	cases := []struct {
+		amount, currency string
+		minor            int64
+		str              string
+	}{
+		{"12461.85", "BRL", 1246185, "12461.85"},
+		{"10.5", "USD", 1050, "10.50"},
+		{"0.05", "EUR", 5, "0.05"},
+		{"-1", "", -100, "-1.00"},
+		{"1500", "JPY", 1500, "1500"},
+		{"1.234", "KWD", 1234, "1.234"},
+	}
+	for _, c := range cases {
+		m, err := ParseMoney(c.amount, c.currency)
+		if err != nil {
+			t.Fatalf("%s %s: %s", c.amount, c.currency, err)
+		}
+		if m.Amount != c.minor || m.String() != c.str {
+			t.Fatalf("%s %s: expected %d %s, got %d %s", c.amount, c.currency, c.minor, c.str, m.Amount, m)
+		}
+	}
+
+	for _, amount := range []string{"1.005", "abc", "1e3", ""} {
+		if _, err := ParseMoney(amount, "BRL"); err == nil {
+			t.Fatalf("expected %q to be rejected", amount)
+		}
+	}
+	if _, err := ParseMoney("1.5", "JPY"); err == nil {
+		t.Fatal("expected decimal places to be rejected for JPY")
+	}
+}
+
+func TestMoney_WithCurrency(t *testing.T) {
+	for _, c := range []struct {
+		m        Money
+		currency string
+		expected Money
+	}{
+		{Money{Amount: 1050}, "USD", Money{1050, "USD"}},
+		{Money{Amount: 1000}, "JPY", Money{10, "JPY"}},
+		{Money{Amount: 1050}, "KWD", Money{10500, "KWD"}},
+		{Money{1050, "BRL"}, "JPY", Money{1050, "BRL"}},
+	} {
+		got, err := c.m.WithCurrency(c.currency)
+		if err != nil || got != c.expected {
+			t.Fatalf("%v in %s: expected %v, got %v %v", c.m, c.currency, c.expected, got, err)
+		}
+	}
+
+	// 10.50 can not be an amount in JPY, it is not rounded
+	if _, err := (Money{Amount: 1050}).WithCurrency("JPY"); !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("expected ErrInvalidAmount, got %v", err)
+	}
+}
+
+func TestMoney_Convert(t *testing.T) {
+	cases := []struct {
+		from        Money
+		rate        float64
+		destination string
+		expected    Money
+	}{
+		// 0.1 * 3 is 0.30000000000000004 with floats
+		{Money{10, "BRL"}, 3, "USD", Money{30, "USD"}},
+		{Money{1246185, "BRL"}, 0.18, "USD", Money{224313, "USD"}},
+		// half rounds away from zero
+		{Money{1, "BRL"}, 0.5, "USD", Money{1, "USD"}},
+		{Money{-1, "BRL"}, 0.5, "USD", Money{-1, "USD"}},
+		{Money{1, "BRL"}, 0.49, "USD", Money{0, "USD"}},
+		// to and from currencies without cents
+		{Money{1050, "USD"}, 105.5, "JPY", Money{1108, "JPY"}},
+		{Money{1108, "JPY"}, 0.0095, "USD", Money{1053, "USD"}},
+		// without currency the amount has cents
+		{Money{1050, ""}, 1, "JPY", Money{11, "JPY"}},
+	}
+	for _, c := range cases {
+		if got := c.from.Convert(c.rate, c.destination); got != c.expected {
+			t.Fatalf("%v %s * %v: expected %v %s, got %v %s", c.from, c.from.Currency, c.rate, c.expected, c.expected.Currency, got, got.Currency)
+		}
+	}
+}
+
+func TestMoney_JSON(t *testing.T) {
+	b, err := json.Marshal(Money{1246185, "BRL"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"amount":"12461.85","currency":"BRL"}` {
+		t.Fatalf("unexpected JSON %s", b)
+	}
+
+	m := Money{}
+	if err := json.Unmarshal(b, &m); err != nil || m != (Money{1246185, "BRL"}) {
+		t.Fatalf("unexpected money %v %v", m, err)
+	}
+
+	// prices stored before they had a currency
+	if err := json.Unmarshal([]byte(`837.37`), &m); err != nil || m != (Money{83737, ""}) {
+		t.Fatalf("unexpected money %v %v", m, err)
+	}
+
+	if err := json.Unmarshal([]byte(`{"amount":"1.5","currency":"JPY"}`), &m); err == nil {
+		t.Fatal("expected decimal places to be rejected for JPY")
+	}
+}
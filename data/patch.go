@@ -0,0 +1,61 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch"
+)
+
+// Media types of the supported patch documents
+const (
+	// JSON Merge Patch https://tools.ietf.org/html/rfc7396
+	MergePatchType = "application/merge-patch+json"
+	// JSON Patch https://tools.ietf.org/html/rfc6902
+	JSONPatchType = "application/json-patch+json"
+)
+
+// ErrUnsupportedPatch is returned when the patch media type is unknown
+var ErrUnsupportedPatch = fmt.Errorf("Unsupported patch, use %s or %s", MergePatchType, JSONPatchType)
+
+// PatchError is returned when the patch document can not be applied
+type PatchError struct {
+	Err error
+}
+
+func (p PatchError) Error() string {
+	return fmt.Sprintf("Unable to apply patch: %s", p.Err)
+}
+
+// ApplyPatch applies the patch document of the given media type to the car
+// and returns the patched copy, the ID of the car can not be changed.
+// The patched car is not validated
+func ApplyPatch(car Car, patchType string, patch []byte) (Car, error) {
+	doc, err := json.Marshal(car)
+	if err != nil {
+		return car, err
+	}
+
+	switch patchType {
+	case MergePatchType:
+		doc, err = jsonpatch.MergePatch(doc, patch)
+	case JSONPatchType:
+		var p jsonpatch.Patch
+		p, err = jsonpatch.DecodePatch(patch)
+		if err == nil {
+			doc, err = p.Apply(doc)
+		}
+	default:
+		return car, ErrUnsupportedPatch
+	}
+	if err != nil {
+		return car, PatchError{err}
+	}
+
+	patched := Car{}
+	if err := json.Unmarshal(doc, &patched); err != nil {
+		return car, PatchError{err}
+	}
+	patched.ID = car.ID
+	return patched, nil
+}
@@ -0,0 +1,30 @@
+package data
+
+import "testing"
+
+func TestApplyPatch(t *testing.T) {
+	car := Car{ID: 1, Name: "Cruze", Description: "A family car", Price: Money{Amount: 1000, Currency: "BRL"}, LicensePlate: "IVP-5464"}
+
+	merged, err := ApplyPatch(car, MergePatchType, []byte(`{"color":"Black","id":9}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.Color != "Black" || merged.Description != car.Description || merged.ID != car.ID {
+		t.Fatalf("unexpected merge result %#v", merged)
+	}
+
+	patched, err := ApplyPatch(car, JSONPatchType, []byte(`[{"op":"replace","path":"/price/amount","value":"20"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patched.Price != (Money{Amount: 2000, Currency: "BRL"}) || patched.Name != car.Name {
+		t.Fatalf("unexpected patch result %#v", patched)
+	}
+
+	if _, err := ApplyPatch(car, JSONPatchType, []byte(`[{"op":"test","path":"/name","value":"Celta"}]`)); err == nil {
+		t.Fatal("expected failed test operation to return an error")
+	}
+	if _, err := ApplyPatch(car, "application/json", nil); err != ErrUnsupportedPatch {
+		t.Fatalf("expected ErrUnsupportedPatch, got %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package data
+
+import (
+	"encoding/xml"
+	"sort"
+)
+
+// Prices holds the price of a car converted to several currencies, keyed by currency
+type Prices map[string]*Conversion
+
+// MarshalXML encodes the prices as a list of <price> elements ordered by
+// currency, encoding/xml does not support maps
+func (p Prices) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	currencies := []string{}
+	for cur := range p {
+		currencies = append(currencies, cur)
+	}
+	sort.Strings(currencies)
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, cur := range currencies {
+		if err := e.EncodeElement(p[cur], xml.StartElement{Name: xml.Name{Local: "price"}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// UnmarshalXML decodes the list of <price> elements
+func (p *Prices) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	doc := struct {
+		Prices []*Conversion `xml:"price"`
+	}{}
+	if err := d.DecodeElement(&doc, &start); err != nil {
+		return err
+	}
+	*p = Prices{}
+	for _, cv := range doc.Prices {
+		(*p)[cv.Currency] = cv
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package data
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Sort orders supported by CarQuery, a leading - sorts descending
+var sortFields = map[string]func(q CarQuery, a, b *Car) bool{
+	"id":    func(q CarQuery, a, b *Car) bool { return a.ID < b.ID },
+	"name":  func(q CarQuery, a, b *Car) bool { return a.Name < b.Name },
+	"price": func(q CarQuery, a, b *Car) bool { return q.price(a) < q.price(b) },
+}
+
+// ErrInvalidSort is returned when the query asks for an unknown sort order
+var ErrInvalidSort = fmt.Errorf("Invalid sort, valid values are id, name and price optionally prefixed with -")
+
+// CarQuery filters, sorts and pages the cars returned by GetCars.
+// The zero value returns every car ordered by ID
+type CarQuery struct {
+	// Max number of cars in the page, 0 means no limit
+	Limit int
+	// Number of cars skipped before the page starts
+	Offset int
+	// Sort order, id, name or price, prefix with - for descending
+	Sort string
+	// Only cars with this color, case insensitive
+	Color string
+	// Only cars whose name starts with this prefix, case insensitive
+	Name string
+	// Only cars with price greater or equal in the base currency, 0 means no filter
+	MinPrice float64
+	// Only cars with price lower or equal in the base currency, 0 means no filter
+	MaxPrice float64
+	// Rates converting the price of a car into the base currency, by the
+	// currency of the car. The price filters and the price sort compare the
+	// prices in the base currency, the repository sets the rates when needed
+	Rates map[string]float64
+}
+
+// ComparesPrices reports whether the query filters or sorts by price,
+// the rates are needed to execute it
+func (q CarQuery) ComparesPrices() bool {
+	return q.MinPrice > 0 || q.MaxPrice > 0 || strings.TrimPrefix(q.Sort, "-") == "price"
+}
+
+// price returns the price of the car in the base currency,
+// prices without a rate are already in the base currency
+func (q CarQuery) price(car *Car) float64 {
+	if rate, ok := q.Rates[car.Price.Currency]; ok {
+		return car.Price.Float64() * rate
+	}
+	return car.Price.Float64()
+}
+
+// Validate checks that the query can be executed
+func (q CarQuery) Validate() error {
+	if q.Limit < 0 || q.Offset < 0 {
+		return fmt.Errorf("Invalid page, limit and offset can not be negative")
+	}
+	if q.MinPrice < 0 || q.MaxPrice < 0 {
+		return fmt.Errorf("Invalid price filter, prices can not be negative")
+	}
+	if q.Sort != "" {
+		if _, ok := sortFields[strings.TrimPrefix(q.Sort, "-")]; !ok {
+			return ErrInvalidSort
+		}
+	}
+	return nil
+}
+
+// Match reports whether the car passes the filters of the query
+func (q CarQuery) Match(car *Car) bool {
+	if q.Color != "" && !strings.EqualFold(car.Color, q.Color) {
+		return false
+	}
+	if q.Name != "" && !strings.HasPrefix(strings.ToLower(car.Name), strings.ToLower(q.Name)) {
+		return false
+	}
+	if q.MinPrice > 0 && q.price(car) < q.MinPrice {
+		return false
+	}
+	if q.MaxPrice > 0 && q.price(car) > q.MaxPrice {
+		return false
+	}
+	return true
+}
+
+// Apply filters, sorts and pages the given cars
+// returns the page and the number of cars matching the filters
+// stores without a native query language use it to honor the query
+func (q CarQuery) Apply(cars Cars) (Cars, int) {
+	matches := Cars{}
+	for _, car := range cars {
+		if q.Match(car) {
+			matches = append(matches, car)
+		}
+	}
+
+	field := strings.TrimPrefix(q.Sort, "-")
+	if field == "" {
+		field = "id"
+	}
+	less := sortFields[field]
+	desc := strings.HasPrefix(q.Sort, "-")
+	sort.SliceStable(matches, func(i, j int) bool {
+		if desc {
+			return less(q, matches[j], matches[i])
+		}
+		return less(q, matches[i], matches[j])
+	})
+
+	total := len(matches)
+	if q.Offset >= total {
+		return Cars{}, total
+	}
+	matches = matches[q.Offset:]
+	if q.Limit > 0 && q.Limit < len(matches) {
+		matches = matches[:q.Limit]
+	}
+	return matches, total
+}
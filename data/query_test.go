@@ -0,0 +1,48 @@
+package data
+
+import "testing"
+
+func TestCarQuery_Apply(t *testing.T) {
+	cars := Cars{
+		{ID: 1, Name: "Cruze", Color: "Blue", Price: Money{Amount: 30000}},
+		{ID: 2, Name: "Celta", Color: "Red", Price: Money{Amount: 10000}},
+		{ID: 3, Name: "Onix", Color: "blue", Price: Money{Amount: 20000}},
+		{ID: 4, Name: "Camaro", Color: "Blue", Price: Money{Amount: 90000}},
+	}
+
+	q := CarQuery{Color: "Blue", MaxPrice: 500, Sort: "-price", Limit: 1}
+	page, total := q.Apply(cars)
+	if total != 2 {
+		t.Fatalf("expected 2 matches, got %d", total)
+	}
+	if len(page) != 1 || page[0].ID != 1 {
+		t.Fatalf("expected car 1 first, got %v", page)
+	}
+
+	q = CarQuery{Name: "c", Sort: "name", Offset: 1}
+	page, total = q.Apply(cars)
+	if total != 3 || len(page) != 2 || page[0].ID != 2 {
+		t.Fatalf("unexpected page %v of %d", page, total)
+	}
+
+	// the prices are compared in the base currency, 100000 JPY is cheaper than 1000 EUR
+	cars = Cars{
+		{ID: 1, Name: "Cruze", Price: Money{Amount: 100000, Currency: "JPY"}},
+		{ID: 2, Name: "Celta", Price: Money{Amount: 50000, Currency: "USD"}},
+		{ID: 3, Name: "Onix", Price: Money{Amount: 100000, Currency: "EUR"}},
+	}
+	rates := map[string]float64{"JPY": 0.0065, "USD": 0.9, "EUR": 1}
+	q = CarQuery{MaxPrice: 1000, Sort: "price", Rates: rates}
+	page, total = q.Apply(cars)
+	if total != 3 || page[0].ID != 2 || page[1].ID != 1 || page[2].ID != 3 {
+		t.Fatalf("expected cars 2, 1 and 3, got %v", page)
+	}
+	q = CarQuery{MinPrice: 700, Rates: rates}
+	if page, total = q.Apply(cars); total != 1 || page[0].ID != 3 {
+		t.Fatalf("expected car 3, got %v", page)
+	}
+
+	if err := (CarQuery{Sort: "color"}).Validate(); err != ErrInvalidSort {
+		t.Fatalf("expected ErrInvalidSort, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package data
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// StalePolicy decides what happens when a cached rate is older than the max age
+type StalePolicy string
+
+const (
+	// serve the stale rate, the response carries a warning
+	StaleServe StalePolicy = "serve"
+	// fetch the rate again from the currency server
+	StaleRefetch StalePolicy = "refetch"
+	// fail the request with ErrStaleRate
+	StaleFail StalePolicy = "fail"
+)
+
+// ErrStaleRate is returned when the cached rate is too old and the policy is StaleFail
+var ErrStaleRate = fmt.Errorf("Currency rate is stale")
+
+// ParseStalePolicy converts the name of a policy into a StalePolicy
+func ParseStalePolicy(s string) (StalePolicy, error) {
+	switch p := StalePolicy(s); p {
+	case StaleServe, StaleRefetch, StaleFail:
+		return p, nil
+	}
+	return "", fmt.Errorf("Unknown stale rate policy %q, use %s, %s or %s", s, StaleServe, StaleRefetch, StaleFail)
+}
+
+// Rate is a conversion rate between two currencies
+type Rate struct {
+	Base        string
+	Destination string
+	Value       float64
+	// when the rate was received from the currency server
+	UpdatedAt time.Time
+	// the rate is older than the max age of the cache
+	Stale bool
+}
+
+// RateCache keeps the last rate received for every pair of currencies
+// and when it was received. Rates older than the max age are stale and
+// handled according to the policy
+type RateCache struct {
+	maxAge time.Duration
+	policy StalePolicy
+
+	mu    sync.RWMutex
+	rates map[string]Rate
+}
+
+// NewRateCache creates an empty cache, a max age of 0 means rates never get stale
+func NewRateCache(maxAge time.Duration, policy StalePolicy) *RateCache {
+	return &RateCache{maxAge: maxAge, policy: policy, rates: make(map[string]Rate)}
+}
+
+// Policy returns what to do with stale rates
+func (rc *RateCache) Policy() StalePolicy {
+	return rc.policy
+}
+
+// Get returns the cached rate between the currencies, Stale tells if it is
+// older than the max age
+func (rc *RateCache) Get(base, destination string) (Rate, bool) {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+
+	r, ok := rc.rates[rateKey(base, destination)]
+	if ok && rc.maxAge > 0 {
+		r.Stale = time.Since(r.UpdatedAt) > rc.maxAge
+	}
+	return r, ok
+}
+
+// Set stores the rate received now
+func (rc *RateCache) Set(base, destination string, value float64) Rate {
+	r := Rate{Base: base, Destination: destination, Value: value, UpdatedAt: time.Now()}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.rates[rateKey(base, destination)] = r
+	return r
+}
+
+// Len returns the number of rates in the cache
+func (rc *RateCache) Len() int {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+
+	return len(rc.rates)
+}
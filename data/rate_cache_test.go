@@ -0,0 +1,40 @@
+package data
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/CassioRoos/grpc_currency/protos/currency"
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestRateCache_StalePolicies(t *testing.T) {
+	for _, policy := range []StalePolicy{StaleServe, StaleRefetch, StaleFail} {
+		fc := &fakeCurrency{rate: 2, updates: make(chan *currency.StreamingRateResponse)}
+		rc := NewRateCache(time.Minute, policy)
+		repo := NewCarsRepository(fc, NewMemoryStore(carList), rc, DefaultCurrency, time.Second, hclog.NewNullLogger())
+
+		// a rate received long ago
+		rc.Set("BRL", "USD", 3)
+		old := rc.rates[rateKey("BRL", "USD")]
+		old.UpdatedAt = time.Now().Add(-time.Hour)
+		rc.rates[rateKey("BRL", "USD")] = old
+
+		car, err := repo.GetCarById(context.Background(), 1, []string{"USD"})
+		switch policy {
+		case StaleServe:
+			if err != nil || car.Conversion.Rate != 3 || !car.Conversion.Stale {
+				t.Fatalf("%s: expected stale rate, got %v %v", policy, car.Conversion, err)
+			}
+		case StaleRefetch:
+			if err != nil || car.Conversion.Rate != 2 || car.Conversion.Stale {
+				t.Fatalf("%s: expected fresh rate, got %v %v", policy, car.Conversion, err)
+			}
+		case StaleFail:
+			if err != ErrStaleRate {
+				t.Fatalf("%s: expected ErrStaleRate, got %v", policy, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/CassioRoos/grpc_currency/protos/currency"
+	"github.com/hashicorp/go-hclog"
+)
+
+// StreamState is the connection state of the rate stream
+type StreamState int
+
+const (
+	// the stream is being opened
+	StreamConnecting StreamState = iota
+	// the stream is open and receiving updates
+	StreamConnected
+	// the stream is closed and waiting to reconnect
+	StreamDisconnected
+)
+
+func (s StreamState) String() string {
+	switch s {
+	case StreamConnecting:
+		return "connecting"
+	case StreamConnected:
+		return "connected"
+	case StreamDisconnected:
+		return "disconnected"
+	}
+	return "unknown"
+}
+
+// ErrStreamNotConnected is returned when a rate can not be sent because the stream is down
+var ErrStreamNotConnected = fmt.Errorf("Rate stream is not connected")
+
+// Backoff computes the wait between reconnection attempts, it grows
+// exponentially from Min up to Max with a random jitter so many
+// instances do not reconnect at the same time
+type Backoff struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+// Duration returns the wait before the given attempt, starting at 0.
+// The wait is a random value between half and the full exponential delay
+func (b Backoff) Duration(attempt int) time.Duration {
+	d := b.Min
+	for i := 0; i < attempt && d < b.Max; i++ {
+		d *= 2
+	}
+	if d > b.Max {
+		d = b.Max
+	}
+	half := int64(d / 2)
+	if half <= 0 {
+		return d
+	}
+	return time.Duration(half + rand.Int63n(half))
+}
+
+// RateStream keeps a subscription open to the rate updates of the currency
+// server. When the stream breaks it reconnects with backoff and sends again
+// every rate requested so far, so no subscription is lost
+type RateStream struct {
+	currency currency.CurrencyClient
+	log      hclog.Logger
+	onRate   func(*currency.RateResponse)
+	backoff  Backoff
+
+	// serializes the calls to Send, a stream does not support concurrent calls.
+	// When both are held it is taken first, a Send blocked by flow control
+	// never blocks State
+	sendMu sync.Mutex
+
+	// guards everything below
+	mu            sync.Mutex
+	stream        currency.Currency_SubscribeRatesClient
+	subscriptions map[string]*currency.RateRequest
+	state         StreamState
+	since         time.Time
+}
+
+// NewRateStream creates a stream calling onRate for every update received,
+// Run must be called to open the stream
+func NewRateStream(c currency.CurrencyClient, l hclog.Logger, onRate func(*currency.RateResponse)) *RateStream {
+	return &RateStream{
+		currency:      c,
+		log:           l,
+		onRate:        onRate,
+		backoff:       Backoff{Min: 500 * time.Millisecond, Max: 30 * time.Second},
+		subscriptions: make(map[string]*currency.RateRequest),
+		state:         StreamDisconnected,
+		since:         time.Now(),
+	}
+}
+
+// State returns the connection state and since when the stream is in it
+func (rs *RateStream) State() (StreamState, time.Time) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	return rs.state, rs.since
+}
+
+// Subscribe asks the server to stream the updates for the given rate.
+// The rate is remembered and requested again after a reconnection,
+// ErrStreamNotConnected is returned while the stream is down
+func (rs *RateStream) Subscribe(rr *currency.RateRequest) error {
+	rs.mu.Lock()
+	rs.subscriptions[rateKey(rr.Base.String(), rr.Destination.String())] = rr
+	stream := rs.stream
+	rs.mu.Unlock()
+
+	if stream == nil {
+		return ErrStreamNotConnected
+	}
+	rs.sendMu.Lock()
+	defer rs.sendMu.Unlock()
+	return stream.Send(rr)
+}
+
+// Run keeps the stream open until the context is done
+func (rs *RateStream) Run(ctx context.Context) {
+	attempt := 0
+	for {
+		rs.setState(StreamConnecting, nil)
+		// every attempt has its own context, canceling it releases the
+		// stream of the attempt whatever the reason it ended
+		actx, cancel := context.WithCancel(ctx)
+		sub, err := rs.currency.SubscribeRates(actx)
+		if err == nil {
+			err = rs.connect(sub)
+		}
+		if err == nil {
+			attempt = 0
+			err = rs.receive(sub)
+		}
+		rs.setState(StreamDisconnected, nil)
+		cancel()
+
+		if ctx.Err() != nil {
+			return
+		}
+		wait := rs.backoff.Duration(attempt)
+		rs.log.Error("Rate stream disconnected", "error", err, "attempt", attempt+1, "retry_in", wait)
+		attempt++
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// connect sets the stream as the current one and sends every known subscription.
+// The subscriptions added meanwhile are sent by Subscribe once connect is done
+func (rs *RateStream) connect(sub currency.Currency_SubscribeRatesClient) error {
+	rs.sendMu.Lock()
+	defer rs.sendMu.Unlock()
+
+	rs.mu.Lock()
+	rs.stream = sub
+	subscriptions := make([]*currency.RateRequest, 0, len(rs.subscriptions))
+	for _, rr := range rs.subscriptions {
+		subscriptions = append(subscriptions, rr)
+	}
+	rs.mu.Unlock()
+
+	for _, rr := range subscriptions {
+		if err := sub.Send(rr); err != nil {
+			return err
+		}
+	}
+	rs.setState(StreamConnected, sub)
+	rs.log.Info("Rate stream connected", "subscriptions", len(subscriptions))
+	return nil
+}
+
+// receive handles the updates until the stream breaks
+func (rs *RateStream) receive(sub currency.Currency_SubscribeRatesClient) error {
+	for {
+		msg, err := sub.Recv()
+		if err != nil {
+			return err
+		}
+		if grpcError := msg.GetError(); grpcError != nil {
+			rs.log.Error("Error subscribing for rates", "error", grpcError.GetMessage())
+			continue
+		}
+		if resp := msg.GetRateResponse(); resp != nil {
+			rs.log.Debug("Update received", "base", resp.Base.String(), "destination", resp.Destination.String(), "rate", resp.Rate)
+			rs.onRate(resp)
+		}
+	}
+}
+
+func (rs *RateStream) setState(s StreamState, stream currency.Currency_SubscribeRatesClient) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.stream = stream
+	if rs.state != s {
+		rs.state = s
+		rs.since = time.Now()
+	}
+}
+
+// rateKey identifies a rate between two currencies
+func rateKey(base, destination string) string {
+	return base + ":" + destination
+}
@@ -0,0 +1,176 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CassioRoos/grpc_currency/protos/currency"
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc"
+)
+
+// flakyCurrency hands out the given streams in order, one per subscription
+type flakyCurrency struct {
+	currency.CurrencyClient
+	mu      sync.Mutex
+	streams []*recordingStream
+	// the context of every subscription
+	contexts []context.Context
+}
+
+func (f *flakyCurrency) SubscribeRates(ctx context.Context, opts ...grpc.CallOption) (currency.Currency_SubscribeRatesClient, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.contexts = append(f.contexts, ctx)
+	if len(f.streams) == 0 {
+		return nil, fmt.Errorf("unavailable")
+	}
+	s := f.streams[0]
+	f.streams = f.streams[1:]
+	return s, nil
+}
+
+// recordingStream records the requests sent, Send fails with sendErr,
+// Recv fails with err or blocks
+type recordingStream struct {
+	grpc.ClientStream
+	err     error
+	sendErr error
+	sent    chan *currency.RateRequest
+}
+
+func (s *recordingStream) Send(rr *currency.RateRequest) error {
+	s.sent <- rr
+	return s.sendErr
+}
+
+func (s *recordingStream) Recv() (*currency.StreamingRateResponse, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	select {}
+}
+
+func TestRateStream_ResubscribesAfterReconnect(t *testing.T) {
+	first := &recordingStream{err: fmt.Errorf("connection reset"), sent: make(chan *currency.RateRequest, 1)}
+	second := &recordingStream{sent: make(chan *currency.RateRequest, 1)}
+	fc := &flakyCurrency{streams: []*recordingStream{first, second}}
+
+	rs := NewRateStream(fc, hclog.NewNullLogger(), func(*currency.RateResponse) {})
+	rs.backoff = Backoff{Min: time.Millisecond, Max: time.Millisecond}
+
+	rr := &currency.RateRequest{Base: currency.Currencies_BRL, Destination: currency.Currencies_USD}
+	if err := rs.Subscribe(rr); err != ErrStreamNotConnected {
+		t.Fatalf("expected ErrStreamNotConnected, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go rs.Run(ctx)
+
+	for _, s := range []*recordingStream{first, second} {
+		select {
+		case got := <-s.sent:
+			if got != rr {
+				t.Fatalf("expected %v to be sent, got %v", rr, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("subscription was not sent")
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if state, _ := rs.State(); state == StreamConnected {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("stream did not reconnect")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRateStream_CancelsFailedAttempts(t *testing.T) {
+	broken := &recordingStream{sendErr: fmt.Errorf("connection reset"), sent: make(chan *currency.RateRequest, 1)}
+	fc := &flakyCurrency{streams: []*recordingStream{broken}}
+
+	rs := NewRateStream(fc, hclog.NewNullLogger(), func(*currency.RateResponse) {})
+	rs.backoff = Backoff{Min: time.Hour, Max: time.Hour}
+	rs.Subscribe(&currency.RateRequest{Base: currency.Currencies_BRL, Destination: currency.Currencies_USD})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go rs.Run(ctx)
+
+	<-broken.sent
+	deadline := time.Now().Add(time.Second)
+	for {
+		fc.mu.Lock()
+		attempts := fc.contexts
+		fc.mu.Unlock()
+		// the stream of the failed attempt is released while waiting to reconnect
+		if len(attempts) == 1 && attempts[0].Err() != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the context of the failed attempt was not canceled")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("the context of Run must stay alive")
+	}
+}
+
+func TestRateStream_StateWhileSendBlocks(t *testing.T) {
+	// nobody reads what is sent, like a stream blocked by flow control
+	stuck := &recordingStream{sent: make(chan *currency.RateRequest)}
+	fc := &flakyCurrency{streams: []*recordingStream{stuck}}
+
+	rs := NewRateStream(fc, hclog.NewNullLogger(), func(*currency.RateResponse) {})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go rs.Run(ctx)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if state, _ := rs.State(); state == StreamConnected {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("stream did not connect")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	go rs.Subscribe(&currency.RateRequest{Base: currency.Currencies_BRL, Destination: currency.Currencies_USD})
+	defer func() { <-stuck.sent }()
+	time.Sleep(10 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		rs.State()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("State blocked by a Send")
+	}
+}
+
+func TestBackoff_Duration(t *testing.T) {
+	b := Backoff{Min: 100 * time.Millisecond, Max: time.Second}
+	for attempt, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		max *= time.Millisecond
+		d := b.Duration(attempt)
+		if d < max/2 || d > max {
+			t.Fatalf("attempt %d: expected between %s and %s, got %s", attempt, max/2, max, d)
+		}
+	}
+}
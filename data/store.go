@@ -0,0 +1,98 @@
+package data
+
+import "fmt"
+
+// CarStore is the persistence layer behind the CarsRepository.
+// The repository deals with currency conversion and delegates
+// every read and write of cars to the store, so new backends can be
+// added without touching the handlers
+type CarStore interface {
+	// All returns every car in the store ordered by ID
+	All() (Cars, error)
+	// Find returns the page of cars matching the query and the
+	// number of cars matching the filters before paging
+	Find(q CarQuery) (Cars, int, error)
+	// Get returns the car with the given ID or ErrCarNotFound
+	Get(id int) (*Car, error)
+	// Add persists a new car, the ID and first version are assigned by the store
+	Add(car *Car) error
+	// AddAll persists all the cars or none of them, IDs and first
+	// versions are assigned by the store
+	AddAll(cars Cars) error
+	// Update replaces the car with the same ID or returns ErrCarNotFound.
+	// When version is not 0 and the stored car has another version
+	// ErrVersionMismatch is returned. The new version is set into the car
+	Update(car *Car, version int) error
+	// Delete removes the car with the given ID or returns ErrCarNotFound.
+	// When version is not 0 and the stored car has another version
+	// ErrVersionMismatch is returned
+	Delete(id int, version int) error
+	// Ping reports whether the store can be read and written
+	Ping() error
+}
+
+// Kinds of store that can be selected through NewCarStore
+const (
+	StoreMemory = "memory"
+	StoreFile   = "file"
+)
+
+// ErrUnknownStore is returned when the requested store kind does not exist
+var ErrUnknownStore = fmt.Errorf("Unknown store, supported stores are %q and %q", StoreMemory, StoreFile)
+
+// NewCarStore creates the store for the given kind
+// path is only used by the file store
+func NewCarStore(kind, path string) (CarStore, error) {
+	switch kind {
+	case StoreMemory:
+		return NewMemoryStore(carList), nil
+	case StoreFile:
+		return NewFileStore(path)
+	default:
+		return nil, ErrUnknownStore
+	}
+}
+
+// nextID returns the ID after the highest ID of the cars, it starts the
+// ID sequence of a store. The stores never reuse an ID afterwards, even when
+// the newest car is deleted, so a version sent in If-Match can only match
+// the car it was read from
+func nextID(cars Cars) int {
+	id := 0
+	for _, car := range cars {
+		if car.ID > id {
+			id = car.ID
+		}
+	}
+	return id + 1
+}
+
+// checkVersion compares the stored car version with the expected one
+// an expected version of 0 matches any version
+func checkVersion(car *Car, version int) error {
+	if version != 0 && car.Version != version {
+		return ErrVersionMismatch
+	}
+	return nil
+}
+
+// indexOf finds the index of a car in the list
+// returns -1 when no car can be found
+func indexOf(cars Cars, id int) int {
+	for i, car := range cars {
+		if car.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// copyCars returns a copy of the list so callers can not change the stored cars
+func copyCars(cars Cars) Cars {
+	cc := make(Cars, 0, len(cars))
+	for _, car := range cars {
+		nc := *car
+		cc = append(cc, &nc)
+	}
+	return cc
+}
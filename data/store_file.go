@@ -0,0 +1,211 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileStore keeps the cars in memory and persists the whole collection
+// as JSON into a file after every change, so the cars survive restarts
+type FileStore struct {
+	mu   sync.RWMutex
+	path string
+	cars Cars
+	// the ID of the next car added, IDs are never reused
+	next int
+}
+
+// storeFile is the content of the store file, the ID sequence is kept
+// with the cars so the IDs of deleted cars are not reused after a restart
+type storeFile struct {
+	NextID int  `json:"next_id"`
+	Cars   Cars `json:"cars"`
+}
+
+// NewFileStore opens the store at the given path
+// when the file does not exist yet the store starts empty and the file
+// is created on the first write
+func NewFileStore(path string) (*FileStore, error) {
+	fs := &FileStore{path: path, cars: Cars{}, next: 1}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return fs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// older files only have the list of cars
+	if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '[' {
+		if err := json.Unmarshal(b, &fs.cars); err != nil {
+			return nil, err
+		}
+		fs.next = nextID(fs.cars)
+		return fs, nil
+	}
+	sf := &storeFile{}
+	if err := json.Unmarshal(b, sf); err != nil {
+		return nil, err
+	}
+	if sf.Cars != nil {
+		fs.cars = sf.Cars
+	}
+	// the sequence never goes back, even when the file was edited by hand
+	fs.next = sf.NextID
+	if id := nextID(fs.cars); id > fs.next {
+		fs.next = id
+	}
+	return fs, nil
+}
+
+// Ping checks that a file can be written next to the store file,
+// as every change writes a temporary file and renames it
+func (fs *FileStore) Ping() error {
+	tmp, err := ioutil.TempFile(filepath.Dir(fs.path), filepath.Base(fs.path)+".ping")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	return os.Remove(tmp.Name())
+}
+
+// All returns a copy of every car in the store
+func (fs *FileStore) All() (Cars, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	return copyCars(fs.cars), nil
+}
+
+// Find returns a copy of the page of cars matching the query
+func (fs *FileStore) Find(q CarQuery) (Cars, int, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	cars, total := q.Apply(copyCars(fs.cars))
+	return cars, total, nil
+}
+
+// Get returns a copy of the car with the given ID
+func (fs *FileStore) Get(id int) (*Car, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	i := indexOf(fs.cars, id)
+	if i == -1 {
+		return nil, ErrCarNotFound
+	}
+	nc := *fs.cars[i]
+	return &nc, nil
+}
+
+// Add appends the car assigning the next ID and persists the store
+func (fs *FileStore) Add(car *Car) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	nc := *car
+	nc.ID = fs.next
+	nc.Version = 1
+	if err := fs.save(append(copyCars(fs.cars), &nc), fs.next+1); err != nil {
+		return err
+	}
+	car.ID = nc.ID
+	car.Version = nc.Version
+	return nil
+}
+
+// AddAll appends all the cars assigning the next IDs and persists the store
+// with a single write, so either every car is stored or none
+func (fs *FileStore) AddAll(cars Cars) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	stored := copyCars(fs.cars)
+	id := fs.next
+	for i, car := range cars {
+		nc := *car
+		nc.ID = id + i
+		nc.Version = 1
+		stored = append(stored, &nc)
+	}
+	if err := fs.save(stored, id+len(cars)); err != nil {
+		return err
+	}
+	for i, car := range cars {
+		car.ID = id + i
+		car.Version = 1
+	}
+	return nil
+}
+
+// Update replaces the car with the same ID when the version matches
+// and persists the store
+func (fs *FileStore) Update(car *Car, version int) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	i := indexOf(fs.cars, car.ID)
+	if i == -1 {
+		return ErrCarNotFound
+	}
+	if err := checkVersion(fs.cars[i], version); err != nil {
+		return err
+	}
+	cars := copyCars(fs.cars)
+	nc := *car
+	nc.Version = fs.cars[i].Version + 1
+	cars[i] = &nc
+	if err := fs.save(cars, fs.next); err != nil {
+		return err
+	}
+	car.Version = nc.Version
+	return nil
+}
+
+// Delete removes the car with the given ID when the version matches
+// and persists the store
+func (fs *FileStore) Delete(id int, version int) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	i := indexOf(fs.cars, id)
+	if i == -1 {
+		return ErrCarNotFound
+	}
+	if err := checkVersion(fs.cars[i], version); err != nil {
+		return err
+	}
+	cars := copyCars(fs.cars)
+	return fs.save(append(cars[:i], cars[i+1:]...), fs.next)
+}
+
+// save writes the cars and the ID sequence into a temporary file and renames
+// it over the store file, a crash in the middle of the write never leaves a
+// broken file. The in memory list is only replaced when the file was written
+func (fs *FileStore) save(cars Cars, next int) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(fs.path), filepath.Base(fs.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := ToJSON(&storeFile{NextID: next, Cars: cars}, tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), fs.path); err != nil {
+		return err
+	}
+	fs.cars = cars
+	fs.next = next
+	return nil
+}
@@ -0,0 +1,114 @@
+package data
+
+import "sync"
+
+// MemoryStore keeps the cars in a slice, everything is lost when
+// the process stops
+type MemoryStore struct {
+	mu   sync.RWMutex
+	cars Cars
+	// the ID of the next car added, IDs are never reused
+	next int
+}
+
+// NewMemoryStore creates a store seeded with a copy of the given cars
+func NewMemoryStore(seed Cars) *MemoryStore {
+	return &MemoryStore{cars: copyCars(seed), next: nextID(seed)}
+}
+
+// Ping always succeeds, the cars are in memory
+func (m *MemoryStore) Ping() error {
+	return nil
+}
+
+// All returns a copy of every car in the store
+func (m *MemoryStore) All() (Cars, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return copyCars(m.cars), nil
+}
+
+// Find returns a copy of the page of cars matching the query
+func (m *MemoryStore) Find(q CarQuery) (Cars, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cars, total := q.Apply(copyCars(m.cars))
+	return cars, total, nil
+}
+
+// Get returns a copy of the car with the given ID
+func (m *MemoryStore) Get(id int) (*Car, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	i := indexOf(m.cars, id)
+	if i == -1 {
+		return nil, ErrCarNotFound
+	}
+	nc := *m.cars[i]
+	return &nc, nil
+}
+
+// Add appends the car to the store assigning the next ID
+func (m *MemoryStore) Add(car *Car) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	car.ID = m.next
+	car.Version = 1
+	m.next++
+	nc := *car
+	m.cars = append(m.cars, &nc)
+	return nil
+}
+
+// AddAll appends all the cars to the store assigning the next IDs
+func (m *MemoryStore) AddAll(cars Cars) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, car := range cars {
+		car.ID = m.next
+		car.Version = 1
+		m.next++
+		nc := *car
+		m.cars = append(m.cars, &nc)
+	}
+	return nil
+}
+
+// Update replaces the car with the same ID when the version matches
+func (m *MemoryStore) Update(car *Car, version int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := indexOf(m.cars, car.ID)
+	if i == -1 {
+		return ErrCarNotFound
+	}
+	if err := checkVersion(m.cars[i], version); err != nil {
+		return err
+	}
+	car.Version = m.cars[i].Version + 1
+	nc := *car
+	m.cars[i] = &nc
+	return nil
+}
+
+// Delete removes the car with the given ID when the version matches
+func (m *MemoryStore) Delete(id int, version int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := indexOf(m.cars, id)
+	if i == -1 {
+		return ErrCarNotFound
+	}
+	if err := checkVersion(m.cars[i], version); err != nil {
+		return err
+	}
+	m.cars = append(m.cars[:i], m.cars[i+1:]...)
+	return nil
+}
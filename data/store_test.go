@@ -0,0 +1,157 @@
+package data
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStore_PersistsAcrossRestarts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cars.json")
+
+	fs, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	car := &Car{Name: "Cruze", Price: Money{Amount: 1000}, LicensePlate: "IVP-5464"}
+	if err := fs.Add(car); err != nil {
+		t.Fatal(err)
+	}
+	if car.ID != 1 {
+		t.Fatalf("expected id 1, got %d", car.ID)
+	}
+
+	reopened, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := reopened.Get(car.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != car.Name {
+		t.Fatalf("expected %q, got %q", car.Name, got.Name)
+	}
+
+	if err := reopened.Update(car, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := reopened.Delete(car.ID, 1); err != ErrVersionMismatch {
+		t.Fatalf("expected ErrVersionMismatch, got %v", err)
+	}
+	if err := reopened.Delete(car.ID, car.Version); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopened.Get(car.ID); err != ErrCarNotFound {
+		t.Fatalf("expected ErrCarNotFound, got %v", err)
+	}
+}
+
+func TestFileStore_Ping(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fs, err := NewFileStore(filepath.Join(dir, "cars.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Fatalf("expected the ping to leave no file, got %d", len(files))
+	}
+
+	os.RemoveAll(dir)
+	if err := fs.Ping(); err == nil {
+		t.Fatal("expected the ping to fail without the directory")
+	}
+}
+
+func TestStores_NeverReuseIDs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cars.json")
+
+	fs, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stores := map[string]CarStore{"memory": NewMemoryStore(Cars{}), "file": fs}
+	for name, s := range stores {
+		deleted := &Car{Name: "Cruze", Price: Money{Amount: 1000}, LicensePlate: "IVP-5464"}
+		if err := s.Add(deleted); err != nil {
+			t.Fatal(err)
+		}
+		// the newest car is deleted, its ID must not come back
+		if err := s.Delete(deleted.ID, deleted.Version); err != nil {
+			t.Fatal(err)
+		}
+		car := &Car{Name: "Celta", Price: Money{Amount: 1000}, LicensePlate: "ABC-1234"}
+		if err := s.Add(car); err != nil {
+			t.Fatal(err)
+		}
+		if car.ID == deleted.ID {
+			t.Fatalf("%s: expected a new id, got the id %d of the deleted car", name, car.ID)
+		}
+		if err := s.Update(&Car{ID: car.ID, Name: "Celta"}, deleted.Version); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+
+	if err := fs.Delete(2, 0); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	car := &Car{Name: "Onix", Price: Money{Amount: 1000}, LicensePlate: "ABC-4321"}
+	if err := reopened.Add(car); err != nil {
+		t.Fatal(err)
+	}
+	if car.ID != 3 {
+		t.Fatalf("expected id 3 after a restart, got %d", car.ID)
+	}
+}
+
+func TestFileStore_ReadsCarList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cars.json")
+	// the format written before the ID sequence was stored
+	legacy := `[{"id":4,"version":2,"name":"Cruze","price":"10.00","license_plate":"IVP-5464"}]`
+	if err := ioutil.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := fs.Get(4); err != nil || got.Version != 2 {
+		t.Fatalf("expected car 4 version 2, got %+v %v", got, err)
+	}
+	car := &Car{Name: "Celta", Price: Money{Amount: 1000}, LicensePlate: "ABC-1234"}
+	if err := fs.Add(car); err != nil {
+		t.Fatal(err)
+	}
+	if car.ID != 5 {
+		t.Fatalf("expected id 5, got %d", car.ID)
+	}
+}
@@ -0,0 +1,29 @@
+package data
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/trace"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/label"
+)
+
+// tracer creates the spans of the repository, the spans are dropped until
+// a tracer provider is installed, see the tracing package
+var tracer = global.Tracer("github.com/CassioRoos/MicroseService/data")
+
+// startSpan starts a child of the span in the context, like the span of the HTTP request
+func startSpan(ctx context.Context, name string, labels ...label.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(labels...))
+}
+
+// endSpan records the error returned by the traced operation and ends the span,
+// it is deferred with a pointer to the named error so the returned value is seen
+func endSpan(ctx context.Context, span trace.Span, err *error) {
+	if *err != nil {
+		span.RecordError(ctx, *err)
+		span.SetStatus(otelcodes.Error, (*err).Error())
+	}
+	span.End()
+}
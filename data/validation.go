@@ -3,13 +3,22 @@ package data
 import (
 	"fmt"
 	"github.com/go-playground/validator"
+	"reflect"
 	"regexp"
+	"strings"
 )
 
 // ValidationError wraps the validators FieldError so we do not
 // expose this to out code
 type ValidationError struct {
 	validator.FieldError
+	value interface{}
+}
+
+// Value returns the value of the field that failed the validation,
+// Money is reported as its decimal amount, not the minor units the rules use
+func (v ValidationError) Value() interface{} {
+	return v.value
 }
 
 func (v ValidationError) Error() string {
@@ -49,17 +58,72 @@ func validateLicensePlate(fl validator.FieldLevel) bool {
 	return false
 }
 
+// validateCarCurrency checks the currency of the price, the price is
+// validated as its amount so its fields can not have their own rules
+func validateCarCurrency(sl validator.StructLevel) {
+	car := sl.Current().Interface().(Car)
+	if cur := car.Price.Currency; cur != "" && !IsCurrencyCode(cur) {
+		sl.ReportError(cur, "price", "Price", "currency", "")
+	}
+}
+
+// moneyAmount validates Money as its amount in minor units so
+// rules like gt=0 apply to the amount
+func moneyAmount(v reflect.Value) interface{} {
+	return v.Interface().(Money).Amount
+}
+
+// fieldValue returns the value of the field of the struct at the namespace,
+// like Car.Price, nil is returned when the field can not be found
+func fieldValue(i interface{}, namespace string) interface{} {
+	v := reflect.ValueOf(i)
+	for _, name := range strings.Split(namespace, ".")[1:] {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return nil
+		}
+		if v = v.FieldByName(name); !v.IsValid() {
+			return nil
+		}
+	}
+	if !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// jsonFieldName returns the name of the field in the JSON document
+// so the errors refer to the fields the client sent
+func jsonFieldName(f reflect.StructField) string {
+	name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
 func NewValidation() *Validation {
 	validate := validator.New()
+	validate.RegisterTagNameFunc(jsonFieldName)
 	validate.RegisterValidation("lcplt", validateLicensePlate)
+	validate.RegisterCustomTypeFunc(moneyAmount, Money{})
+	validate.RegisterStructValidation(validateCarCurrency, Car{})
 	return &Validation{validate}
 }
 
-// Validate the item
-// for more detail the returned error can be cast into a
-// validator.ValidationErrors collection
+// Validate the item, the error is set when the item can not be validated,
+// like a nil pointer, the field errors are returned otherwise.
+// For more detail every field error wraps a validator.FieldError
 //
-// if ve, ok := err.(validator.ValidationErrors); ok {
+// for _, ve := range errs {
 //			fmt.Println(ve.Namespace())
 //			fmt.Println(ve.Field())
 //			fmt.Println(ve.StructNamespace())
@@ -72,22 +136,29 @@ func NewValidation() *Validation {
 //			fmt.Println(ve.Param())
 //			fmt.Println()
 //	}
-func (v *Validation) Validate(i interface{}) ValidationErrors {
+func (v *Validation) Validate(i interface{}) (ValidationErrors, error) {
 	errsValidation := v.validate.Struct(i)
 	if errsValidation == nil {
-		return nil
+		return nil, nil
 	}
 
-	errs := errsValidation.(validator.ValidationErrors)
+	// like a nil car, the item can not be validated at all
+	errs, ok := errsValidation.(validator.ValidationErrors)
+	if !ok {
+		return nil, errsValidation
+	}
 
 	if len(errs) == 0 {
-		return nil
+		return nil, nil
 	}
 	var returnErrs []ValidationError
 	for _, err := range errs {
-		ve := ValidationError{err.(validator.FieldError)}
+		ve := ValidationError{FieldError: err, value: err.Value()}
+		if m, ok := fieldValue(i, err.StructNamespace()).(Money); ok && err.Value() == m.Amount {
+			ve.value = m.String()
+		}
 		returnErrs = append(returnErrs, ve)
 	}
 
-	return returnErrs
+	return returnErrs, nil
 }
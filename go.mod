@@ -4,6 +4,7 @@ go 1.14
 
 require (
 	github.com/CassioRoos/grpc_currency v0.0.0-20200816014156-115e60de24fd
+	github.com/evanphx/json-patch v4.5.0+incompatible
 	github.com/go-openapi/runtime v0.19.20
 	github.com/go-playground/universal-translator v0.17.0 // indirect
 	github.com/go-playground/validator v9.31.0+incompatible
@@ -11,7 +12,13 @@ require (
 	github.com/gorilla/mux v1.7.4
 	github.com/hashicorp/go-hclog v0.14.1
 	github.com/leodido/go-urn v1.2.0 // indirect
-	github.com/nicholasjackson/env v0.6.0
-	google.golang.org/grpc v1.31.0
+	github.com/prometheus/client_golang v1.7.1
+	go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc v0.13.0
+	go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp v0.13.0
+	go.opentelemetry.io/otel v0.13.0
+	go.opentelemetry.io/otel/exporters/stdout v0.13.0
+	go.opentelemetry.io/otel/sdk v0.13.0
+	google.golang.org/grpc v1.32.0
 	gopkg.in/go-playground/assert.v1 v1.2.1 // indirect
+	gopkg.in/yaml.v2 v2.3.0
 )
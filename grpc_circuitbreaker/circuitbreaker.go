@@ -0,0 +1,177 @@
+package grpc_circuitbreaker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/CassioRoos/grpc_currency/protos/currency"
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// State is the state of the circuit breaker
+type State int
+
+const (
+	// calls go through, failures are counted
+	Closed State = iota
+	// calls fail fast until the cool down ends
+	Open
+	// a single trial call goes through, its result closes or opens the circuit again
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// ErrOpen is returned without calling the server while the circuit is open.
+// It is an Unavailable status so callers handle it like a server that is down
+var ErrOpen = status.Error(codes.Unavailable, "Circuit breaker is open, the currency server is not called")
+
+// CircuitBreaker wraps a currency client and stops calling the server after
+// a number of consecutive failures. After the cool down a single call is
+// let through, when it succeeds the circuit closes, otherwise it opens again.
+//
+// Only GetRate goes through the breaker, the rate stream reconnects with its own backoff
+type CircuitBreaker struct {
+	currency.CurrencyClient
+	log       hclog.Logger
+	threshold int
+	coolDown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	// a trial call is running while half-open
+	trial bool
+	// increased on every change of state, the result of a call only counts
+	// when the state did not change since the call started
+	generation int
+}
+
+// NewCircuitBreaker wraps the client, the circuit opens after threshold
+// consecutive failures and stays open for the cool down
+func NewCircuitBreaker(c currency.CurrencyClient, l hclog.Logger, threshold int, coolDown time.Duration) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &CircuitBreaker{CurrencyClient: c, log: l, threshold: threshold, coolDown: coolDown, now: time.Now}
+}
+
+// State returns the current state of the circuit
+func (cb *CircuitBreaker) State() State {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	return cb.state
+}
+
+// GetRate calls the server unless the circuit is open, then ErrOpen is returned
+func (cb *CircuitBreaker) GetRate(ctx context.Context, in *currency.RateRequest, opts ...grpc.CallOption) (*currency.RateResponse, error) {
+	generation, err := cb.allow()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cb.CurrencyClient.GetRate(ctx, in, opts...)
+	cb.done(generation, err)
+	return resp, err
+}
+
+// allow reports whether a call can go through,
+// the generation of the circuit is returned to be passed to done
+func (cb *CircuitBreaker) allow() (int, error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case Open:
+		if cb.now().Sub(cb.openedAt) < cb.coolDown {
+			return 0, ErrOpen
+		}
+		cb.setState(HalfOpen)
+		cb.trial = true
+	case HalfOpen:
+		if cb.trial {
+			return 0, ErrOpen
+		}
+		cb.trial = true
+	}
+	return cb.generation, nil
+}
+
+// done records the result of a call started in the given generation
+func (cb *CircuitBreaker) done(generation int, err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	// a call started before the last change of state, like a slow call started
+	// while closed finishing during the trial, does not change the circuit.
+	// While half-open the only call of the generation is the trial
+	if generation != cb.generation {
+		return
+	}
+	failed := isFailure(err)
+	switch cb.state {
+	case Closed:
+		if !failed {
+			cb.failures = 0
+			return
+		}
+		cb.failures++
+		if cb.failures >= cb.threshold {
+			cb.open()
+		}
+	case HalfOpen:
+		cb.trial = false
+		switch {
+		case failed:
+			cb.open()
+		case err == nil:
+			cb.failures = 0
+			cb.setState(Closed)
+		}
+		// like a canceled trial, nothing was learned about the server,
+		// the circuit stays half-open and the next call is the trial
+	}
+}
+
+func (cb *CircuitBreaker) open() {
+	cb.openedAt = cb.now()
+	cb.setState(Open)
+}
+
+func (cb *CircuitBreaker) setState(s State) {
+	if cb.state == s {
+		return
+	}
+	cb.log.Warn("Currency circuit breaker changed state", "from", cb.state, "to", s, "failures", cb.failures, "cool_down", cb.coolDown)
+	cb.state = s
+	cb.generation++
+}
+
+// isFailure reports whether the error means the server is in trouble,
+// errors caused by the request or by the client going away do not count
+func isFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.NotFound, codes.Canceled, codes.FailedPrecondition, codes.OutOfRange:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,156 @@
+package grpc_circuitbreaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/CassioRoos/grpc_currency/protos/currency"
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// stubCurrency answers every call with err and counts the calls
+type stubCurrency struct {
+	currency.CurrencyClient
+	err   error
+	calls int
+}
+
+func (s *stubCurrency) GetRate(ctx context.Context, in *currency.RateRequest, opts ...grpc.CallOption) (*currency.RateResponse, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &currency.RateResponse{Rate: 1}, nil
+}
+
+func TestCircuitBreaker_Transitions(t *testing.T) {
+	now := time.Now()
+	sc := &stubCurrency{err: status.Error(codes.Unavailable, "down")}
+	cb := NewCircuitBreaker(sc, hclog.NewNullLogger(), 3, time.Minute)
+	cb.now = func() time.Time { return now }
+	rr := &currency.RateRequest{}
+
+	for i := 0; i < 3; i++ {
+		cb.GetRate(context.Background(), rr)
+	}
+	if cb.State() != Open || sc.calls != 3 {
+		t.Fatalf("expected open after 3 calls, got %s after %d", cb.State(), sc.calls)
+	}
+
+	// fails fast while open
+	if _, err := cb.GetRate(context.Background(), rr); err != ErrOpen || sc.calls != 3 {
+		t.Fatalf("expected ErrOpen without calling the server, got %v after %d", err, sc.calls)
+	}
+
+	// the trial call fails and opens the circuit again
+	now = now.Add(time.Minute)
+	cb.GetRate(context.Background(), rr)
+	if cb.State() != Open || sc.calls != 4 {
+		t.Fatalf("expected open after a failed trial, got %s after %d", cb.State(), sc.calls)
+	}
+
+	// the trial call succeeds and closes the circuit
+	now = now.Add(time.Minute)
+	sc.err = nil
+	if _, err := cb.GetRate(context.Background(), rr); err != nil || cb.State() != Closed {
+		t.Fatalf("expected closed after a successful trial, got %s %v", cb.State(), err)
+	}
+}
+
+func TestCircuitBreaker_IgnoresClientErrors(t *testing.T) {
+	sc := &stubCurrency{err: status.Error(codes.InvalidArgument, "same currencies")}
+	cb := NewCircuitBreaker(sc, hclog.NewNullLogger(), 1, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		cb.GetRate(context.Background(), &currency.RateRequest{})
+	}
+	if cb.State() != Closed {
+		t.Fatalf("expected closed, got %s", cb.State())
+	}
+}
+
+// gatedCurrency blocks every call until the test sends its result
+// through the channel received from calls
+type gatedCurrency struct {
+	currency.CurrencyClient
+	calls chan chan error
+}
+
+func (g *gatedCurrency) GetRate(ctx context.Context, in *currency.RateRequest, opts ...grpc.CallOption) (*currency.RateResponse, error) {
+	result := make(chan error)
+	g.calls <- result
+	if err := <-result; err != nil {
+		return nil, err
+	}
+	return &currency.RateResponse{Rate: 1}, nil
+}
+
+func TestCircuitBreaker_IgnoresCallsFromOtherStates(t *testing.T) {
+	now := time.Now()
+	gc := &gatedCurrency{calls: make(chan chan error)}
+	cb := NewCircuitBreaker(gc, hclog.NewNullLogger(), 1, time.Minute)
+	cb.now = func() time.Time { return now }
+	down := status.Error(codes.Unavailable, "down")
+
+	call := func() (chan error, chan struct{}) {
+		finished := make(chan struct{})
+		go func() {
+			defer close(finished)
+			cb.GetRate(context.Background(), &currency.RateRequest{})
+		}()
+		return <-gc.calls, finished
+	}
+
+	// a slow call starts while closed, another one fails and opens the circuit
+	slow, slowFinished := call()
+	failing, failingFinished := call()
+	failing <- down
+	<-failingFinished
+	if cb.State() != Open {
+		t.Fatalf("expected open, got %s", cb.State())
+	}
+
+	// the slow call succeeds while the trial is running, the circuit stays half-open
+	now = now.Add(time.Minute)
+	trial, trialFinished := call()
+	slow <- nil
+	<-slowFinished
+	if cb.State() != HalfOpen {
+		t.Fatalf("expected half-open until the trial finishes, got %s", cb.State())
+	}
+
+	// only the trial decides
+	trial <- down
+	<-trialFinished
+	if cb.State() != Open {
+		t.Fatalf("expected open after a failed trial, got %s", cb.State())
+	}
+}
+
+func TestCircuitBreaker_CanceledTrial(t *testing.T) {
+	now := time.Now()
+	sc := &stubCurrency{err: status.Error(codes.Unavailable, "down")}
+	cb := NewCircuitBreaker(sc, hclog.NewNullLogger(), 1, time.Minute)
+	cb.now = func() time.Time { return now }
+	rr := &currency.RateRequest{}
+
+	cb.GetRate(context.Background(), rr)
+	now = now.Add(time.Minute)
+
+	// the client went away during the trial, the circuit does not close
+	sc.err = status.Error(codes.Canceled, "context canceled")
+	cb.GetRate(context.Background(), rr)
+	if cb.State() != HalfOpen {
+		t.Fatalf("expected half-open after a canceled trial, got %s", cb.State())
+	}
+
+	// the next call is the new trial
+	sc.err = nil
+	if _, err := cb.GetRate(context.Background(), rr); err != nil || cb.State() != Closed {
+		t.Fatalf("expected closed after a successful trial, got %s %v", cb.State(), err)
+	}
+}
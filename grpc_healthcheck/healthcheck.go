@@ -4,31 +4,69 @@ import (
 	"context"
 	"github.com/CassioRoos/grpc_currency/protos/healthcheck"
 	"github.com/hashicorp/go-hclog"
-	"os"
+	"sync"
 	"time"
 )
 
 type grpcHealthCheck struct {
 	log hclog.Logger
 	h   healthcheck.HealthCheckClient
+
+	mu      sync.RWMutex
+	healthy bool
 }
 
 func NewGrpcHealthCheck(log hclog.Logger, h healthcheck.HealthCheckClient) *grpcHealthCheck {
 	return &grpcHealthCheck{log: log, h: h}
 }
 
-func (h *grpcHealthCheck) HealthCheck(times int) bool {
-	for i := 0; i <= times; i++ {
-		resp, err := h.h.Check(context.Background(), &healthcheck.HealthCheckParam{})
-		if err != nil && i == times {
-			h.log.Error("GRPC is unhealty and wont connect", "ERROR", err)
-			os.Exit(1)
-		}
-		if err == nil {
-			h.log.Info("GRPC is healthy", "Message", resp.Message)
-			return true
+// Watch probes the server now and every interval until the context is done,
+// a probe not answered within the interval fails
+func (h *grpcHealthCheck) Watch(ctx context.Context, interval time.Duration) {
+	probe := func() bool {
+		pctx, cancel := context.WithTimeout(ctx, interval)
+		defer cancel()
+		return h.probe(pctx)
+	}
+	if !probe() {
+		h.log.Error("GRPC is unhealthy, running without the currency server until it recovers")
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			probe()
 		}
-		time.Sleep(1 * time.Second)
 	}
-	return false
+}
+
+// Healthy reports whether the last probe was answered
+func (h *grpcHealthCheck) Healthy() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.healthy
+}
+
+// probe checks the server once and logs when its health changes
+func (h *grpcHealthCheck) probe(ctx context.Context) bool {
+	resp, err := h.h.Check(ctx, &healthcheck.HealthCheckParam{})
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	was := h.healthy
+	h.healthy = err == nil
+	switch {
+	case err == nil && !was:
+		h.log.Info("GRPC is healthy", "Message", resp.Message)
+	case err != nil && was:
+		h.log.Error("GRPC is unhealthy", "ERROR", err)
+	case err != nil:
+		h.log.Debug("GRPC is still unhealthy", "ERROR", err)
+	}
+	return h.healthy
 }
@@ -0,0 +1,57 @@
+package grpc_healthcheck
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CassioRoos/grpc_currency/protos/healthcheck"
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc"
+)
+
+// switchHealth answers the probes with the configured error
+type switchHealth struct {
+	mu  sync.Mutex
+	err error
+}
+
+func (s *switchHealth) Check(ctx context.Context, in *healthcheck.HealthCheckParam, opts ...grpc.CallOption) (*healthcheck.HealthCheckReturn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &healthcheck.HealthCheckReturn{Message: "OK"}, nil
+}
+
+func (s *switchHealth) set(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+func TestGrpcHealthCheck_WatchRecovers(t *testing.T) {
+	sh := &switchHealth{err: errors.New("connection refused")}
+	h := NewGrpcHealthCheck(hclog.NewNullLogger(), sh)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go h.Watch(ctx, 5*time.Millisecond)
+
+	time.Sleep(20 * time.Millisecond)
+	if h.Healthy() {
+		t.Fatal("expected unhealthy while the server is down")
+	}
+
+	sh.set(nil)
+	deadline := time.Now().Add(time.Second)
+	for !h.Healthy() {
+		if time.Now().After(deadline) {
+			t.Fatal("expected healthy after the server recovered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/CassioRoos/MicroseService/data"
+)
+
+// Modes of a batch import
+const (
+	// every car is stored or none of them
+	BatchAtomic = "atomic"
+	// valid cars are stored even when others fail
+	BatchBestEffort = "best-effort"
+)
+
+// MaxBatchSize is the max number of cars accepted by a batch import
+const MaxBatchSize = 1000
+
+// MaxBatchBytes is the max size of the body of a batch import
+const MaxBatchBytes = 4 << 20
+
+// errBodyTooLarge is the message of the error returned by http.MaxBytesReader
+// once the limit is reached, the error has no type of its own before Go 1.19
+const errBodyTooLarge = "http: request body too large"
+
+// BatchResult is the outcome of a single car of a batch import
+type BatchResult struct {
+	// Position of the car in the request
+	Index int `json:"index"`
+	// HTTP status for the car, 201 when it was created
+	Status int `json:"status"`
+	// The stored car
+	Car *data.Car `json:"car,omitempty"`
+	// Explanation of the failure
+	Detail string `json:"detail,omitempty"`
+	// Fields that failed the validation
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// BatchResponse reports the outcome of every car of a batch import
+type BatchResponse struct {
+	// Mode of the import, atomic or best-effort
+	Mode string `json:"mode"`
+	// Number of cars created
+	Created int `json:"created"`
+	// Number of cars not created
+	Failed int `json:"failed"`
+	// Outcome of each car, in the order they were sent
+	Results []BatchResult `json:"results"`
+}
+
+// swagger:route POST /cars:batch cars importCars
+// Create many cars at once, the body is a JSON array of cars or a newline
+// delimited JSON (application/x-ndjson) stream of cars.
+// In atomic mode (default) no car is created if any car is invalid, in
+// best-effort mode the valid cars are created and the invalid ones reported
+//
+// Consumes:
+// - application/json
+// - application/x-ndjson
+//
+// responses:
+// 	201: batchResponse
+// 	207: batchResponse
+// 	400: errorResponse
+// 	413: errorResponse
+// 	422: batchResponse
+// 	500: errorResponse
+
+// ImportCars handles POST requests with many cars
+func (c *Cars) ImportCars(rw http.ResponseWriter, r *http.Request) {
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = BatchAtomic
+	}
+	if mode != BatchAtomic && mode != BatchBestEffort {
+		writeProblem(rw, r, http.StatusBadRequest, fmt.Errorf("Invalid mode, use %s or %s", BatchAtomic, BatchBestEffort))
+		return
+	}
+	c.logger(r).Debug("Handle POST batch", "mode", mode)
+
+	// the decoding stops at the first car over the limit or when the body is too large
+	body := http.MaxBytesReader(rw, r.Body, MaxBatchBytes)
+	cars, err := data.FromJSONStream(body, MaxBatchSize)
+	switch {
+	case errors.Is(err, data.ErrTooManyCars):
+		writeProblem(rw, r, http.StatusRequestEntityTooLarge, fmt.Errorf("The batch has more than %d cars", MaxBatchSize))
+		return
+	case err != nil && err.Error() == errBodyTooLarge:
+		writeProblem(rw, r, http.StatusRequestEntityTooLarge, fmt.Errorf("The batch is larger than %d bytes", MaxBatchBytes))
+		return
+	case err != nil:
+		writeProblem(rw, r, http.StatusBadRequest, err)
+		return
+	}
+	if len(cars) == 0 {
+		writeProblem(rw, r, http.StatusBadRequest, fmt.Errorf("The batch has no cars"))
+		return
+	}
+
+	resp := &BatchResponse{Mode: mode, Results: make([]BatchResult, len(cars))}
+	valid := []int{}
+	for i, car := range cars {
+		resp.Results[i].Index = i
+		errs, err := c.v.Validate(car)
+		if err != nil {
+			resp.Results[i].Status = http.StatusBadRequest
+			resp.Results[i].Detail = err.Error()
+			continue
+		}
+		if len(errs) != 0 {
+			resp.Results[i].Status = http.StatusUnprocessableEntity
+			resp.Results[i].Errors = fieldErrors(errs)
+			continue
+		}
+		valid = append(valid, i)
+	}
+
+	if mode == BatchAtomic {
+		c.importAtomic(rw, r, cars, valid, resp)
+		return
+	}
+
+	for _, i := range valid {
+		nc, err := c.cr.AddCar(r.Context(), cars[i])
+		if errors.Is(err, data.ErrInvalidAmount) {
+			resp.Results[i].Status = http.StatusBadRequest
+			resp.Results[i].Detail = err.Error()
+			continue
+		}
+		if err != nil {
+			c.logger(r).Error("Unable to add car", "index", i, "error", err)
+			resp.Results[i].Status = http.StatusInternalServerError
+			resp.Results[i].Detail = errInternal.Error()
+			continue
+		}
+		resp.Results[i].Status = http.StatusCreated
+		resp.Results[i].Car = nc
+		resp.Created++
+	}
+	resp.Failed = len(cars) - resp.Created
+
+	status := http.StatusCreated
+	if resp.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	writeBatchResponse(rw, status, resp)
+}
+
+// importAtomic stores the valid cars only when every car of the batch is valid
+func (c *Cars) importAtomic(rw http.ResponseWriter, r *http.Request, cars data.Cars, valid []int, resp *BatchResponse) {
+	if len(valid) != len(cars) {
+		// the valid cars are not created because of the invalid ones
+		for _, i := range valid {
+			resp.Results[i].Status = http.StatusFailedDependency
+			resp.Results[i].Detail = "Not created, other cars of the batch are invalid"
+		}
+		resp.Failed = len(cars)
+		writeBatchResponse(rw, http.StatusUnprocessableEntity, resp)
+		return
+	}
+
+	stored, err := c.cr.AddCars(r.Context(), cars)
+	if err != nil {
+		c.writeError(rw, r, err)
+		return
+	}
+	for i, car := range stored {
+		resp.Results[i].Status = http.StatusCreated
+		resp.Results[i].Car = car
+	}
+	resp.Created = len(stored)
+	writeBatchResponse(rw, http.StatusCreated, resp)
+}
+
+func writeBatchResponse(rw http.ResponseWriter, status int, resp *BatchResponse) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	data.ToJSON(resp, rw)
+}
+
+// swagger:route GET /cars/export cars exportCars
+// Stream every car as newline delimited JSON (default) or CSV.
+// The format is chosen by the format parameter or the Accept header
+//
+// Produces:
+// - application/x-ndjson
+// - text/csv
+//
+// responses:
+// 	200: exportResponse
+// 	400: errorResponse
+
+// ExportCars handles GET requests streaming all the cars
+func (c *Cars) ExportCars(rw http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "ndjson"
+		if strings.Contains(r.Header.Get("Accept"), "text/csv") {
+			format = "csv"
+		}
+	}
+	c.logger(r).Debug("Handle GET export", "format", format)
+
+	var write func(*data.Car) error
+	switch format {
+	case "ndjson":
+		rw.Header().Set("Content-Type", "application/x-ndjson")
+		write = func(car *data.Car) error {
+			// the encoder ends every car with a new line
+			return data.ToJSON(car, rw)
+		}
+	case "csv":
+		rw.Header().Set("Content-Type", "text/csv")
+		rw.Header().Set("Content-Disposition", `attachment; filename="cars.csv"`)
+		cw := data.NewCSVWriter(rw)
+		if err := cw.WriteHeader(); err != nil {
+			c.logger(r).Error("Unable to export cars", "error", err)
+			return
+		}
+		write = cw.Write
+	default:
+		writeProblem(rw, r, http.StatusBadRequest, fmt.Errorf("Invalid format, use ndjson or csv"))
+		return
+	}
+
+	// the status is sent with the first car, errors after it can only be logged
+	if err := c.cr.ExportCars(r.Context(), write); err != nil {
+		c.logger(r).Error("Unable to export cars", "error", err)
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const (
+	validCar   = `{"name":"Celta","price":{"amount":"10.00"},"license_plate":"ABC-1234"}`
+	invalidCar = `{"name":"Onix","price":{"amount":"10.00"},"license_plate":"invalid"}`
+)
+
+func decodeBatch(t *testing.T, body string) *BatchResponse {
+	t.Helper()
+	resp := &BatchResponse{}
+	if err := json.Unmarshal([]byte(body), resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestImportCars_Atomic(t *testing.T) {
+	sm, _ := newTestRouter(nil)
+
+	rw := serve(sm, http.MethodPost, "/cars:batch", "["+validCar+","+invalidCar+"]", nil)
+	if rw.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d %s", rw.Code, rw.Body)
+	}
+	resp := decodeBatch(t, rw.Body.String())
+	if resp.Created != 0 || resp.Failed != 2 || resp.Results[0].Status != http.StatusFailedDependency || resp.Results[1].Status != http.StatusUnprocessableEntity {
+		t.Fatalf("expected no car created, got %+v", resp)
+	}
+	if rw = serve(sm, http.MethodGet, "/cars", "", nil); rw.Header().Get("X-Total-Count") != "1" {
+		t.Fatalf("expected only the seeded car, got %s", rw.Header().Get("X-Total-Count"))
+	}
+
+	// newline delimited JSON
+	rw = serve(sm, http.MethodPost, "/cars:batch", validCar+"\n"+validCar+"\n", map[string]string{"Content-Type": "application/x-ndjson"})
+	if rw.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d %s", rw.Code, rw.Body)
+	}
+	if resp := decodeBatch(t, rw.Body.String()); resp.Created != 2 || resp.Results[1].Car.ID != 3 {
+		t.Fatalf("expected cars 2 and 3, got %+v", resp)
+	}
+}
+
+func TestImportCars_BestEffort(t *testing.T) {
+	sm, _ := newTestRouter(nil)
+
+	rw := serve(sm, http.MethodPost, "/cars:batch?mode=best-effort", "["+validCar+","+invalidCar+"]", nil)
+	if rw.Code != http.StatusMultiStatus {
+		t.Fatalf("expected 207, got %d %s", rw.Code, rw.Body)
+	}
+	resp := decodeBatch(t, rw.Body.String())
+	if resp.Created != 1 || resp.Failed != 1 || resp.Results[0].Status != http.StatusCreated || resp.Results[0].Car.ID != 2 {
+		t.Fatalf("expected the valid car created, got %+v", resp)
+	}
+	if r := resp.Results[1]; r.Status != http.StatusUnprocessableEntity || len(r.Errors) != 1 || r.Errors[0].Field != "license_plate" {
+		t.Fatalf("expected the license plate reported, got %+v", r)
+	}
+}
+
+func TestImportCars_InvalidBatch(t *testing.T) {
+	sm, _ := newTestRouter(nil)
+
+	tooMany := "[" + strings.Repeat(validCar+",", MaxBatchSize) + validCar + "]"
+	tooLarge := fmt.Sprintf(`[{"name":%q}]`, strings.Repeat("a", MaxBatchBytes))
+	for name, tc := range map[string]struct {
+		target string
+		body   string
+		status int
+	}{
+		"null car":     {"/cars:batch", "[" + validCar + ",null]", http.StatusBadRequest},
+		"broken json":  {"/cars:batch", "[" + validCar, http.StatusBadRequest},
+		"empty":        {"/cars:batch", "[]", http.StatusBadRequest},
+		"unknown mode": {"/cars:batch?mode=all", "[" + validCar + "]", http.StatusBadRequest},
+		"too many":     {"/cars:batch", tooMany, http.StatusRequestEntityTooLarge},
+		"too large":    {"/cars:batch", tooLarge, http.StatusRequestEntityTooLarge},
+	} {
+		rw := serve(sm, http.MethodPost, tc.target, tc.body, nil)
+		if rw.Code != tc.status {
+			t.Errorf("%s: expected %d, got %d %s", name, tc.status, rw.Code, rw.Body)
+			continue
+		}
+		decodeProblem(t, rw)
+	}
+}
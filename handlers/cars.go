@@ -6,6 +6,7 @@ import (
 	"github.com/hashicorp/go-hclog"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 )
@@ -13,7 +14,14 @@ import (
 type Cars struct {
 	l  hclog.Logger
 	v  *data.Validation
+	e  *data.EncoderRegistry
 	cr data.CarsRepositoryInterface
+	ch CurrencyHealth
+}
+
+// CurrencyHealth reports whether the currency server is answering
+type CurrencyHealth interface {
+	Healthy() bool
 }
 
 type KeyCar struct{}
@@ -26,22 +34,92 @@ type carsResponse struct {
 	Body []data.Car
 }
 
-func NewCars(l hclog.Logger, v *data.Validation, cr data.CarsRepositoryInterface) *Cars {
-	return &Cars{l: l, v: v, cr: cr}
+func NewCars(l hclog.Logger, v *data.Validation, e *data.EncoderRegistry, cr data.CarsRepositoryInterface, ch CurrencyHealth) *Cars {
+	return &Cars{l: l, v: v, e: e, cr: cr, ch: ch}
 }
 
 // ErrInvalidCarPath is an error message when the car path is not valid
 var ErrInvalidCarPath = fmt.Errorf("Invalid Path, path should be /cars/[id]")
 
-// GenericError is a generic error message returned by a server
-type GenericError struct {
-	Code    int    `json:"code,omitempty"`
-	Message string `json:"message,omitempty"`
+// negotiate returns the encoder for the Accept header and sets its media type
+// as the Content-Type of the response.
+// A 406 problem is written when no encoder is acceptable
+func (c *Cars) negotiate(rw http.ResponseWriter, r *http.Request) (data.Encoder, bool) {
+	rw.Header().Add("Vary", "Accept")
+	enc, err := c.e.Negotiate(r.Header.Get("Accept"))
+	if err != nil {
+		writeProblem(rw, r, http.StatusNotAcceptable, fmt.Errorf("%s, supported media types are %s", err, strings.Join(c.e.MediaTypes(), ", ")))
+		return nil, false
+	}
+	rw.Header().Set("Content-Type", enc.MediaTypes()[0])
+	return enc, true
+}
+
+// warnStaleRates adds a Warning header to converted responses when a rate
+// is older than the max age or the rate stream is down, as the cached
+// rates are not being updated
+func (c *Cars) warnStaleRates(rw http.ResponseWriter, cars ...*data.Car) {
+	// a warning for every stale currency, not for every car
+	warned := map[string]bool{}
+	for _, car := range cars {
+		for _, cv := range conversions(car) {
+			if cv.Stale && !warned[cv.Currency] {
+				warned[cv.Currency] = true
+				rw.Header().Add("Warning", fmt.Sprintf(
+					`110 - "Currency rate for %s is stale, last updated %s"`,
+					cv.Currency,
+					cv.UpdatedAt.UTC().Format(http.TimeFormat),
+				))
+			}
+		}
+	}
+
+	if len(cars) == 0 || len(conversions(cars[0])) == 0 {
+		return
+	}
+	state, since := c.cr.RateStreamState()
+	if state == data.StreamConnected {
+		return
+	}
+	rw.Header().Add("Warning", fmt.Sprintf(
+		`110 - "Currency rates may be stale, rate stream %s since %s"`,
+		state,
+		since.UTC().Format(http.TimeFormat),
+	))
+}
+
+// currencyAvailable writes a 503 problem while the currency server is down
+// and reports whether the currencies can be used
+func (c *Cars) currencyAvailable(rw http.ResponseWriter, r *http.Request) bool {
+	if c.ch.Healthy() {
+		return true
+	}
+	writeProblem(rw, r, http.StatusServiceUnavailable, data.ErrCurrencyUnavailable)
+	return false
 }
 
-// ValidationError is a collection of validation error messages
-type ValidationError struct {
-	Messages []string `json:"messages"`
+// conversions returns the rates applied to the price of the car
+func conversions(car *data.Car) []*data.Conversion {
+	cvs := []*data.Conversion{}
+	if car.Conversion != nil {
+		cvs = append(cvs, car.Conversion)
+	}
+	for _, cv := range car.Prices {
+		cvs = append(cvs, cv)
+	}
+	return cvs
+}
+
+// getCurrencies returns the currencies of the currency query parameter,
+// a comma separated list like USD,EUR,GBP
+func getCurrencies(r *http.Request) []string {
+	currencies := []string{}
+	for _, cur := range strings.Split(r.URL.Query().Get("currency"), ",") {
+		if cur = strings.TrimSpace(cur); cur != "" {
+			currencies = append(currencies, cur)
+		}
+	}
+	return currencies
 }
 
 // getCarId returns the car id from the URL
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CassioRoos/MicroseService/data"
+	"github.com/CassioRoos/grpc_currency/protos/currency"
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc"
+)
+
+// fakeCurrency answers every rate with 2, the rate updates never come
+type fakeCurrency struct {
+	currency.CurrencyClient
+}
+
+func (f *fakeCurrency) GetRate(ctx context.Context, in *currency.RateRequest, opts ...grpc.CallOption) (*currency.RateResponse, error) {
+	return &currency.RateResponse{Base: in.Base, Destination: in.Destination, Rate: 2}, nil
+}
+
+func (f *fakeCurrency) SubscribeRates(ctx context.Context, opts ...grpc.CallOption) (currency.Currency_SubscribeRatesClient, error) {
+	return &idleStream{}, nil
+}
+
+type idleStream struct {
+	grpc.ClientStream
+}
+
+func (s *idleStream) Send(*currency.RateRequest) error {
+	return nil
+}
+
+func (s *idleStream) Recv() (*currency.StreamingRateResponse, error) {
+	// the stream never ends during the tests
+	select {}
+}
+
+// healthy is the state of the currency server
+type healthy bool
+
+func (h healthy) Healthy() bool {
+	return bool(h)
+}
+
+// newTestRouter routes the car handlers like main does, the store has
+// the car 1 priced 10.00 EUR and the base currency is EUR
+func newTestRouter(store data.CarStore) (*mux.Router, *data.RateCache) {
+	if store == nil {
+		store = data.NewMemoryStore(data.Cars{
+			{ID: 1, Version: 1, Name: "Cruze", Price: data.Money{Amount: 1000, Currency: "EUR"}, LicensePlate: "IVP-5464"},
+		})
+	}
+	rc := data.NewRateCache(time.Minute, data.StaleRefetch)
+	repo := data.NewCarsRepository(&fakeCurrency{}, store, rc, "EUR", time.Second, hclog.NewNullLogger())
+	c := NewCars(hclog.NewNullLogger(), data.NewValidation(), data.NewDefaultEncoders(), repo, healthy(true))
+
+	sm := mux.NewRouter()
+	getRouter := sm.Methods(http.MethodGet).Subrouter()
+	getRouter.HandleFunc("/cars", c.GetListCars)
+	getRouter.HandleFunc("/cars/{id:[0-9]+}", c.GetCarById)
+
+	putRouter := sm.Methods(http.MethodPut).Subrouter()
+	putRouter.HandleFunc("/cars/{id:[0-9]+}", c.UpdateCar)
+	putRouter.Use(c.MiddlewareValidateCar)
+
+	postRouter := sm.Methods(http.MethodPost).Subrouter()
+	postRouter.HandleFunc("/cars", c.PostCar)
+	postRouter.Use(c.MiddlewareValidateCar)
+
+	patchRouter := sm.Methods(http.MethodPatch).Subrouter()
+	patchRouter.HandleFunc("/cars/{id:[0-9]+}", c.PatchCar)
+
+	batchRouter := sm.Methods(http.MethodPost).Subrouter()
+	batchRouter.HandleFunc("/cars:batch", c.ImportCars)
+
+	deleteRouter := sm.Methods(http.MethodDelete).Subrouter()
+	deleteRouter.HandleFunc("/cars/{id:[0-9]+}", c.DeleteCar)
+	return sm, rc
+}
+
+// serve sends the request to the router and returns the recorded response
+func serve(h http.Handler, method, target, body string, header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, r)
+	return rw
+}
+
+// decodeProblem reads a problem response, the test fails when it is not one
+func decodeProblem(t *testing.T, rw *httptest.ResponseRecorder) *Problem {
+	t.Helper()
+	if ct := rw.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("expected a problem, got %s %s", ct, rw.Body)
+	}
+	p := &Problem{}
+	if err := json.NewDecoder(rw.Body).Decode(p); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestCars_Negotiation(t *testing.T) {
+	sm, _ := newTestRouter(nil)
+
+	rw := serve(sm, http.MethodGet, "/cars/1", "", map[string]string{"Accept": "image/png"})
+	if rw.Code != http.StatusNotAcceptable {
+		t.Fatalf("expected 406, got %d", rw.Code)
+	}
+	decodeProblem(t, rw)
+
+	rw = serve(sm, http.MethodPost, "/cars", "name=Celta", map[string]string{"Content-Type": "text/plain"})
+	if rw.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected 415, got %d", rw.Code)
+	}
+	decodeProblem(t, rw)
+
+	rw = serve(sm, http.MethodGet, "/cars/1", "", map[string]string{"Accept": "application/xml"})
+	if rw.Code != http.StatusOK || rw.Header().Get("Content-Type") != "application/xml" {
+		t.Fatalf("expected an XML car, got %d %s", rw.Code, rw.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/CassioRoos/MicroseService/data"
+)
+
+// swagger:route GET /currencies currencies listCurrencies
+// Returns the currencies the prices can be converted to
+// responses:
+// 		200: currenciesResponse
+// 		503: errorResponse
+
+// ListCurrencies handles GET requests and returns the currencies supported by the currency server
+func (c *Cars) ListCurrencies(rw http.ResponseWriter, r *http.Request) {
+	c.logger(r).Debug("Handle GET List Currencies")
+	if !c.currencyAvailable(rw, r) {
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	if err := data.ToJSON(data.Currencies(), rw); err != nil {
+		c.logger(r).Error("Unable to serialize currencies", "error", err)
+	}
+}
@@ -7,10 +7,17 @@
 //
 // Consumes:
 // - application/json
+// - application/xml
+// - text/csv
+// - application/yaml
 //
 // Produces:
 //
 // - application/json
+// - application/xml
+// - text/csv
+// - application/yaml
+// - application/problem+json
 // swagger:meta
 package handlers
 
@@ -22,20 +29,20 @@ import (
 // these types are not used by anu of the handlers
 // DO NOT USE THIS TYPES ANYWHERE BUT FOR DOCUMENTATION
 
-// Generic error message returned as a string
+// Error message returned as application/problem+json (RFC 7807)
 // swagger:response errorResponse
 type errorResponseWrapper struct {
 	// Description of the error
 	// in: body
-	Body GenericError
+	Body Problem
 }
 
-// Validation errors defines as an array os strings
+// Validation problem listing every field that failed the validation
 // swagger:response errorValidation
 type erroValidationWrapper struct {
-	// Collection of the errors
+	// Problem with the failing fields in errors
 	// in: body
-	Body ValidationError
+	Body Problem
 }
 
 // A list of cars
@@ -58,7 +65,7 @@ type carResponseWrapper struct {
 // swagger:response noContentResponse
 type noContentResponseWrapper struct{}
 
-// swagger:parameters updateCar createCar
+// swagger:parameters updateCar createCars
 type carParamsWrapper struct {
 	// Car data structure to Update or Create.
 	// Note: the Id field is ignored by both Create and Update operations
@@ -67,10 +74,130 @@ type carParamsWrapper struct {
 	Body data.Car
 }
 
-//swagger:parameters updateCar
+//swagger:parameters updateCar patchCar getCar deleteCar
 type carIdParamsWrapper struct {
 	// the Id of the car for which the operation relates
 	// in: path
 	// required: true
 	Id int `json:"id"`
-}
\ No newline at end of file
+}
+
+// swagger:parameters patchCar
+type carPatchParamsWrapper struct {
+	// JSON Merge Patch object or JSON Patch array of operations,
+	// the patched car must be a valid car
+	// in: body
+	// required: true
+	Body interface{}
+}
+
+// swagger:parameters updateCar patchCar deleteCar
+type carIfMatchParamsWrapper struct {
+	// ETag of the car, the operation fails with 412 when the car has another version
+	// in: header
+	IfMatch string `json:"If-Match"`
+}
+
+// swagger:parameters getCar
+type carIfNoneMatchParamsWrapper struct {
+	// ETag of the car, 304 is returned while the car has this version and the rates did not change
+	// in: header
+	IfNoneMatch string `json:"If-None-Match"`
+	// Currencies used to convert the price, a comma separated list like USD,EUR,GBP.
+	// With more than one currency the converted prices are returned in the prices of the car
+	// in: query
+	Currency string `json:"currency"`
+}
+
+// swagger:parameters listCars
+type carsQueryParamsWrapper struct {
+	// Max number of cars in the page, every car is returned when not set
+	// in: query
+	// minimum: 0
+	Limit int `json:"limit"`
+	// Number of cars skipped before the page starts
+	// in: query
+	// minimum: 0
+	Offset int `json:"offset"`
+	// Sort order, one of id, name or price, prefix with - for descending.
+	// The prices are sorted in the base currency
+	// in: query
+	Sort string `json:"sort"`
+	// Only cars with this color
+	// in: query
+	Color string `json:"color"`
+	// Only cars whose name starts with this prefix
+	// in: query
+	Name string `json:"name"`
+	// Only cars with price greater or equal, in the base currency
+	// in: query
+	MinPrice float64 `json:"min_price"`
+	// Only cars with price lower or equal, in the base currency
+	// in: query
+	MaxPrice float64 `json:"max_price"`
+	// Currencies used to convert the prices, a comma separated list like USD,EUR,GBP.
+	// With more than one currency the converted prices are returned in the prices of the car
+	// in: query
+	Currency string `json:"currency"`
+}
+
+// Outcome of every car of a batch import
+// swagger:response batchResponse
+type batchResponseWrapper struct {
+	// in: body
+	Body BatchResponse
+}
+
+// The currencies supported by the currency server
+// swagger:response currenciesResponse
+type currenciesResponseWrapper struct {
+	// in: body
+	Body []data.CurrencyInfo
+}
+
+// Status of the service and of every check
+// swagger:response healthResponse
+type healthResponseWrapper struct {
+	// in: body
+	Body HealthReport
+}
+
+// The level of the logs
+// swagger:response logLevelResponse
+type logLevelResponseWrapper struct {
+	// in: body
+	Body LogLevelDoc
+}
+
+// swagger:parameters setLogLevel
+type logLevelParamsWrapper struct {
+	// The new level of the logs
+	// in: body
+	// required: true
+	Body LogLevelDoc
+}
+
+// Every car as newline delimited JSON or CSV
+// swagger:response exportResponse
+type exportResponseWrapper struct {
+	// in: body
+	Body string
+}
+
+// swagger:parameters importCars
+type importCarsParamsWrapper struct {
+	// atomic (default) or best-effort
+	// in: query
+	Mode string `json:"mode"`
+	// JSON array of cars or newline delimited JSON with a car per line
+	// in: body
+	// required: true
+	Body []data.Car
+}
+
+// swagger:parameters exportCars
+type exportCarsParamsWrapper struct {
+	// ndjson (default) or csv
+	// in: query
+	Format string `json:"format"`
+}
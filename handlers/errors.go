@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/CassioRoos/MicroseService/data"
+)
+
+// Problem types returned in the type member of a Problem,
+// problems without a specific type use about:blank and are described by the status
+const (
+	ProblemTypeBlank      = "about:blank"
+	ProblemTypeValidation = "/problems/validation-error"
+)
+
+// Problem is the error message returned by the server, it follows
+// RFC 7807 and is sent as application/problem+json
+// https://tools.ietf.org/html/rfc7807
+type Problem struct {
+	// URI reference identifying the problem type
+	Type string `json:"type"`
+	// Short summary of the problem type
+	Title string `json:"title"`
+	// HTTP status code of the response
+	Status int `json:"status"`
+	// Explanation specific to this occurrence of the problem
+	Detail string `json:"detail,omitempty"`
+	// Path of the request that raised the problem
+	Instance string `json:"instance,omitempty"`
+	// Fields that failed the validation, only set for validation problems
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes a field that failed the validation
+type FieldError struct {
+	// Name of the field in the JSON document
+	Field string `json:"field"`
+	// Validation rule that failed, like required or gt
+	Tag string `json:"tag"`
+	// Parameter of the rule, like 0 for gt=0
+	Param string `json:"param,omitempty"`
+	// Value sent for the field
+	Value interface{} `json:"value"`
+}
+
+// writeProblem writes a problem with the given status and the error as detail
+func writeProblem(rw http.ResponseWriter, r *http.Request, status int, err error) {
+	p := &Problem{
+		Type:     ProblemTypeBlank,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Instance: r.URL.Path,
+	}
+	if err != nil {
+		p.Detail = err.Error()
+	}
+	renderProblem(rw, p)
+}
+
+// writeValidationProblem writes a 422 problem listing every field that failed the validation
+func writeValidationProblem(rw http.ResponseWriter, r *http.Request, errs data.ValidationErrors) {
+	p := &Problem{
+		Type:     ProblemTypeValidation,
+		Title:    "Validation failed",
+		Status:   http.StatusUnprocessableEntity,
+		Detail:   "One or more fields are invalid",
+		Instance: r.URL.Path,
+		Errors:   fieldErrors(errs),
+	}
+	renderProblem(rw, p)
+}
+
+// fieldErrors converts the validation errors into the FieldErrors sent to the client
+func fieldErrors(errs data.ValidationErrors) []FieldError {
+	fe := []FieldError{}
+	for _, e := range errs {
+		fe = append(fe, FieldError{
+			Field: e.Field(),
+			Tag:   e.Tag(),
+			Param: e.Param(),
+			Value: e.Value(),
+		})
+	}
+	return fe
+}
+
+// errInternal is the detail of the internal errors, the error itself is logged
+// with the request ID as it can hold details like paths of the server
+var errInternal = fmt.Errorf("Internal error, the request ID of the response identifies it in the logs")
+
+// writeError writes the problem for an error returned by the repository,
+// known errors get their own status, everything else is an internal error
+func (c *Cars) writeError(rw http.ResponseWriter, r *http.Request, err error) {
+	// the errors wrapping the known errors carry details like the currency
+	switch {
+	case errors.Is(err, data.ErrUnsupportedCurrency), errors.Is(err, data.ErrInvalidAmount):
+		writeProblem(rw, r, http.StatusBadRequest, err)
+		return
+	case errors.Is(err, data.ErrCurrencyUnavailable):
+		writeProblem(rw, r, http.StatusServiceUnavailable, err)
+		return
+	case errors.Is(err, data.ErrRateTimeout):
+		writeProblem(rw, r, http.StatusGatewayTimeout, err)
+		return
+	case errors.Is(err, context.Canceled):
+		// the client went away, nobody reads the response
+		c.logger(r).Debug("Request canceled", "method", r.Method, "path", r.URL.Path)
+		return
+	}
+	switch err {
+	case data.ErrCarNotFound:
+		writeProblem(rw, r, http.StatusNotFound, err)
+	case data.ErrVersionMismatch:
+		writeProblem(rw, r, http.StatusPreconditionFailed, err)
+	case data.ErrInvalidSort:
+		writeProblem(rw, r, http.StatusBadRequest, err)
+	case data.ErrStaleRate:
+		writeProblem(rw, r, http.StatusServiceUnavailable, err)
+	default:
+		c.logger(r).Error("Unexpected error", "method", r.Method, "path", r.URL.Path, "error", err)
+		writeProblem(rw, r, http.StatusInternalServerError, errInternal)
+	}
+}
+
+func renderProblem(rw http.ResponseWriter, p *Problem) {
+	rw.Header().Set("Content-Type", "application/problem+json")
+	rw.WriteHeader(p.Status)
+	data.ToJSON(p, rw)
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/CassioRoos/MicroseService/data"
+)
+
+// failingStore fails every write with an error holding details of the server
+type failingStore struct {
+	data.CarStore
+}
+
+func (failingStore) Add(car *data.Car) error {
+	return errors.New("open /var/lib/cars/cars.json: permission denied")
+}
+
+func TestCars_InternalErrorHidesDetails(t *testing.T) {
+	sm, _ := newTestRouter(failingStore{data.NewMemoryStore(data.Cars{})})
+
+	body := `{"name":"Celta","price":{"amount":"10.00"},"license_plate":"ABC-1234"}`
+	rw := serve(sm, http.MethodPost, "/cars", body, map[string]string{"Content-Type": "application/json"})
+	if rw.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rw.Code)
+	}
+	if p := decodeProblem(t, rw); strings.Contains(p.Detail, "cars.json") {
+		t.Fatalf("expected a generic detail, got %s", p.Detail)
+	}
+}
+
+func TestCars_ValidationProblem(t *testing.T) {
+	sm, _ := newTestRouter(nil)
+
+	body := `{"name":"Celta","price":{"amount":"-1.00"},"license_plate":"ABC-1234"}`
+	rw := serve(sm, http.MethodPost, "/cars", body, map[string]string{"Content-Type": "application/json"})
+	if rw.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d %s", rw.Code, rw.Body)
+	}
+	p := decodeProblem(t, rw)
+	if p.Type != ProblemTypeValidation || len(p.Errors) != 1 {
+		t.Fatalf("expected a validation problem with 1 error, got %+v", p)
+	}
+	if fe := p.Errors[0]; fe.Field != "price" || fe.Tag != "gt" || fe.Value != "-1.00" {
+		t.Fatalf("unexpected field error %+v", fe)
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/CassioRoos/MicroseService/data"
+)
+
+// etag returns the entity tag for the car representation.
+// The tag is strong and holds the version of the car, a converted
+// car is a different representation so it gets a weak tag holding the version
+// and a hash of the currencies and the rates applied, a new rate changes the tag
+func etag(car *data.Car, currencies []string) string {
+	if len(currencies) == 0 {
+		return fmt.Sprintf(`"%d"`, car.Version)
+	}
+	h := fnv.New32a()
+	for _, cur := range currencies {
+		cv := car.Conversion
+		if car.Prices != nil {
+			cv = car.Prices[cur]
+		}
+		rate := 0.0
+		if cv != nil {
+			rate = cv.Rate
+		}
+		fmt.Fprintf(h, "%s=%s;", cur, strconv.FormatFloat(rate, 'g', -1, 64))
+	}
+	return fmt.Sprintf(`W/"%d-%08x"`, car.Version, h.Sum32())
+}
+
+// ifMatchVersion returns the car version required by the If-Match header.
+// 0 means no precondition, the header is missing or is *.
+// -1 is returned for a tag that is not a car version, it never matches
+func ifMatchVersion(r *http.Request) int {
+	h := strings.TrimSpace(r.Header.Get("If-Match"))
+	if h == "" || h == "*" {
+		return 0
+	}
+	// If-Match uses the strong comparison, weak tags never match
+	if !strings.HasPrefix(h, `"`) || !strings.HasSuffix(h, `"`) {
+		return -1
+	}
+	v, err := strconv.Atoi(strings.Trim(h, `"`))
+	if err != nil || v <= 0 {
+		return -1
+	}
+	return v
+}
+
+// notModified reports whether any tag of the If-None-Match header matches
+// the given tag, If-None-Match uses the weak comparison
+func notModified(r *http.Request, tag string) bool {
+	h := r.Header.Get("If-None-Match")
+	if h == "" {
+		return false
+	}
+	for _, t := range strings.Split(h, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == strings.TrimPrefix(tag, "W/") {
+			return true
+		}
+	}
+	return false
+}
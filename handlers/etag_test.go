@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIfMatchVersion(t *testing.T) {
+	for h, version := range map[string]int{"": 0, "*": 0, `"3"`: 3, `W/"3"`: -1, `"abc"`: -1, `"0"`: -1, "3": -1} {
+		r := httptest.NewRequest(http.MethodPut, "/cars/1", nil)
+		r.Header.Set("If-Match", h)
+		if v := ifMatchVersion(r); v != version {
+			t.Errorf("If-Match %s: expected %d, got %d", h, version, v)
+		}
+	}
+}
+
+func TestETag_Preconditions(t *testing.T) {
+	sm, _ := newTestRouter(nil)
+
+	rw := serve(sm, http.MethodGet, "/cars/1", "", nil)
+	if rw.Code != http.StatusOK || rw.Header().Get("ETag") != `"1"` {
+		t.Fatalf("expected the tag of version 1, got %d %s", rw.Code, rw.Header().Get("ETag"))
+	}
+
+	rw = serve(sm, http.MethodGet, "/cars/1", "", map[string]string{"If-None-Match": `"1"`})
+	if rw.Code != http.StatusNotModified || rw.Body.Len() != 0 {
+		t.Fatalf("expected 304 without body, got %d", rw.Code)
+	}
+
+	body := `{"name":"Cruze LT","price":{"amount":"12.00"},"license_plate":"IVP-5464"}`
+	header := map[string]string{"Content-Type": "application/json", "If-Match": `"2"`}
+	rw = serve(sm, http.MethodPut, "/cars/1", body, header)
+	if rw.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412 for another version, got %d", rw.Code)
+	}
+	decodeProblem(t, rw)
+
+	header["If-Match"] = `"1"`
+	rw = serve(sm, http.MethodPut, "/cars/1", body, header)
+	if rw.Code != http.StatusOK || rw.Header().Get("ETag") != `"2"` {
+		t.Fatalf("expected the tag of version 2, got %d %s", rw.Code, rw.Header().Get("ETag"))
+	}
+
+	// the client still holding version 1 gets the new version
+	rw = serve(sm, http.MethodGet, "/cars/1", "", map[string]string{"If-None-Match": `"1"`})
+	if rw.Code != http.StatusOK {
+		t.Fatalf("expected 200 for an old tag, got %d", rw.Code)
+	}
+
+	rw = serve(sm, http.MethodDelete, "/cars/1", "", map[string]string{"If-Match": `"1"`})
+	if rw.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412 deleting an old version, got %d", rw.Code)
+	}
+}
+
+func TestETag_ChangesWithRate(t *testing.T) {
+	sm, rc := newTestRouter(nil)
+
+	rw := serve(sm, http.MethodGet, "/cars/1?currency=USD", "", nil)
+	tag := rw.Header().Get("ETag")
+	if rw.Code != http.StatusOK || tag == "" || tag == `"1"` {
+		t.Fatalf("expected a weak tag for the converted car, got %d %s", rw.Code, tag)
+	}
+	rw = serve(sm, http.MethodGet, "/cars/1?currency=USD", "", map[string]string{"If-None-Match": tag})
+	if rw.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 while the rate is the same, got %d", rw.Code)
+	}
+
+	rc.Set("EUR", "USD", 3)
+	rw = serve(sm, http.MethodGet, "/cars/1?currency=USD", "", map[string]string{"If-None-Match": tag})
+	if rw.Code != http.StatusOK || rw.Header().Get("ETag") == tag {
+		t.Fatalf("expected a new tag after the rate changed, got %d %s", rw.Code, rw.Header().Get("ETag"))
+	}
+}
@@ -1,33 +1,54 @@
 package handlers
 
 import (
-	"github.com/CassioRoos/MicroseService/data"
 	"net/http"
+
+	"github.com/CassioRoos/MicroseService/data"
 )
 
 // swagger:route GET /cars cars listCars
-// Returns a list of cars
+// Returns a page of cars, the total of cars matching the filters is returned
+// in the X-Total-Count header and the links to other pages in the Link header
 // responses:
 // 		200: carsResponse
+// 		400: errorResponse
+// 		406: errorResponse
+// 		500: errorResponse
+// 		503: errorResponse
+// 		504: errorResponse
 
-// ListAll handles GET requests and returns all current cars
+// ListAll handles GET requests and returns the cars matching the query
 func (c *Cars) GetListCars(rw http.ResponseWriter, r *http.Request) {
-	c.l.Debug("Handle GET List Cars")
-	rw.Header().Add("Content-Type", "application/json")
-	cur := r.URL.Query().Get("currency")
+	c.logger(r).Debug("Handle GET List Cars")
+	enc, ok := c.negotiate(rw, r)
+	if !ok {
+		return
+	}
+	q, err := parseCarQuery(r)
+	if err != nil {
+		writeProblem(rw, r, http.StatusBadRequest, err)
+		return
+	}
+	cur := getCurrencies(r)
+	if err := data.ValidateCurrencies(cur...); err != nil {
+		writeProblem(rw, r, http.StatusBadRequest, err)
+		return
+	}
+	if len(cur) > 0 && !c.currencyAvailable(rw, r) {
+		return
+	}
 	// Return the type CARS
-	lc,err := c.cr.GetCars(cur)
-	if err !=nil{
-	    rw.WriteHeader(http.StatusInternalServerError)
-		data.ToJSON(&GenericError{http.StatusInternalServerError, err.Error()}, rw)
+	lc, total, err := c.cr.GetCars(r.Context(), q, cur)
+	if err != nil {
+		c.writeError(rw, r, err)
 		return
 	}
-	// FROM the type we can call the method to JSON, this way we can optimise our code
-	//r.Header.Add("Content-Type","application/json")
-
-	if err := data.ToJSON(lc, rw); err != nil {
-		c.l.Error("Unable to serialize car", "Error", err)
-		http.Error(rw, "Unable to marshal JSON", http.StatusInternalServerError)
+	setPageHeaders(rw, r, q, total)
+	c.warnStaleRates(rw, lc...)
+	// the encoder writes straight into the response, no buffer is needed
+	if err := enc.Encode(lc, rw); err != nil {
+		// the status is already sent, the error can only be logged
+		c.logger(r).Error("Unable to serialize car", "error", err)
 		return
 	}
 
@@ -35,34 +56,55 @@ func (c *Cars) GetListCars(rw http.ResponseWriter, r *http.Request) {
 	// rw.Write(data)
 }
 
+// swagger:route GET /cars/{id} cars getCar
+// Returns a single car, the ETag header holds the car version, and the rates
+// when the price is converted.
+// A request with If-None-Match matching the current tag returns 304
+// responses:
+// 		200: carResponse
+// 		304: noContentResponse
+// 		400: errorResponse
+// 		404: errorResponse
+// 		406: errorResponse
+// 		500: errorResponse
+// 		503: errorResponse
+// 		504: errorResponse
+
+// GetCarById handles GET requests for a single car
 func (c *Cars) GetCarById(rw http.ResponseWriter, r *http.Request) {
-	rw.Header().Add("Content-Type", "application/json")
+	enc, ok := c.negotiate(rw, r)
+	if !ok {
+		return
+	}
 	id := getCarId(r)
-	c.l.Debug("Handle GET car by id:", id)
-	cur := r.URL.Query().Get("currency")
+	c.logger(r).Debug("Handle GET car", "id", id)
+	cur := getCurrencies(r)
+	if err := data.ValidateCurrencies(cur...); err != nil {
+		writeProblem(rw, r, http.StatusBadRequest, err)
+		return
+	}
+	if len(cur) > 0 && !c.currencyAvailable(rw, r) {
+		return
+	}
 
-	car, err := c.cr.GetCarById(id, cur)
-	switch err {
-	case nil:
-	case data.ErrCarNotFound:
-		{
-			c.l.Error("[ERROR] fetching car", err)
-			rw.WriteHeader(http.StatusNotFound)
-			data.ToJSON(&GenericError{http.StatusNotFound, err.Error()}, rw)
-			return
-		}
-	default:
-		c.l.Error("[ERROR] fetching car", err)
-		rw.WriteHeader(http.StatusInternalServerError)
-		data.ToJSON(&GenericError{http.StatusInternalServerError, err.Error()}, rw)
+	car, err := c.cr.GetCarById(r.Context(), id, cur)
+	if err != nil {
+		c.writeError(rw, r, err)
 		return
 	}
 
+	c.warnStaleRates(rw, car)
+	tag := etag(car, cur)
+	rw.Header().Set("ETag", tag)
+	if notModified(r, tag) {
+		rw.WriteHeader(http.StatusNotModified)
+		return
+	}
 
-	err = data.ToJSON(car, rw)
+	err = enc.Encode(car, rw)
 	if err != nil {
 		// should never happen, but will log it - Defense coding
-		c.l.Error("[ERROR] Serializing car ", err)
+		c.logger(r).Error("Unable to serialize car", "error", err)
 	}
 
 }
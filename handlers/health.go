@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/CassioRoos/MicroseService/data"
+	"github.com/CassioRoos/MicroseService/logging"
+	"github.com/hashicorp/go-hclog"
+)
+
+// Status of a check and of the whole service
+const (
+	StatusOK = "ok"
+	// a non critical check failed, the service keeps serving
+	StatusDegraded = "degraded"
+	StatusFail     = "fail"
+)
+
+// CheckFunc checks a component, it returns nil when the component is healthy
+type CheckFunc func(ctx context.Context) error
+
+// check is a CheckFunc registered with its name
+type check struct {
+	name     string
+	critical bool
+	fn       CheckFunc
+}
+
+// Health serves the liveness and readiness probes.
+// The readiness runs every registered check, a failed critical check makes
+// the service not ready, a failed non critical check only degrades it
+type Health struct {
+	l       hclog.Logger
+	timeout time.Duration
+	checks  []check
+}
+
+// HealthReport is returned by the probes
+// swagger:model
+type HealthReport struct {
+	// ok, degraded or fail
+	Status string `json:"status"`
+	// Outcome of every check, only set by the readiness probe
+	Checks []CheckResult `json:"checks,omitempty"`
+}
+
+// CheckResult is the outcome of a check
+type CheckResult struct {
+	// Name of the checked component
+	Name string `json:"name"`
+	// ok, degraded or fail
+	Status string `json:"status"`
+	// A failed critical check makes the service not ready
+	Critical bool `json:"critical"`
+	// Time taken by the check, like 1.2ms
+	Latency string `json:"latency"`
+	// Why the check failed
+	Error string `json:"error,omitempty"`
+}
+
+// NewHealth creates the probes, every check must end within the timeout
+func NewHealth(l hclog.Logger, timeout time.Duration) *Health {
+	return &Health{l: l, timeout: timeout}
+}
+
+// AddCheck registers a check run by the readiness probe
+func (h *Health) AddCheck(name string, critical bool, fn CheckFunc) {
+	h.checks = append(h.checks, check{name: name, critical: critical, fn: fn})
+}
+
+// swagger:route GET /healthz health liveness
+// Returns 200 while the process is alive
+// responses:
+// 		200: healthResponse
+
+// Live handles the liveness probe, the process answering is enough
+func (h *Health) Live(rw http.ResponseWriter, r *http.Request) {
+	writeHealth(rw, http.StatusOK, &HealthReport{Status: StatusOK})
+}
+
+// swagger:route GET /readyz health readiness
+// Returns the outcome of every check, 503 when a critical check fails
+// responses:
+// 		200: healthResponse
+// 		503: healthResponse
+
+// Ready handles the readiness probe, the checks run in parallel
+func (h *Health) Ready(rw http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+
+	results := make([]CheckResult, len(h.checks))
+	var wg sync.WaitGroup
+	for i, c := range h.checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			results[i] = h.run(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := &HealthReport{Status: StatusOK, Checks: results}
+	status := http.StatusOK
+	for _, res := range results {
+		switch {
+		case res.Status == StatusFail:
+			report.Status = StatusFail
+			status = http.StatusServiceUnavailable
+		case res.Status == StatusDegraded && report.Status == StatusOK:
+			report.Status = StatusDegraded
+		}
+	}
+	writeHealth(rw, status, report)
+}
+
+// run executes the check, a check not ending within the timeout fails
+func (h *Health) run(ctx context.Context, c check) CheckResult {
+	start := time.Now()
+	errc := make(chan error, 1)
+	go func() { errc <- c.fn(ctx) }()
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	res := CheckResult{Name: c.name, Status: StatusOK, Critical: c.critical, Latency: time.Since(start).String()}
+	if err != nil {
+		res.Error = err.Error()
+		res.Status = StatusDegraded
+		if c.critical {
+			res.Status = StatusFail
+		}
+		logging.FromContext(ctx, h.l).Debug("Readiness check failed", "check", c.name, "critical", c.critical, "error", err)
+	}
+	return res
+}
+
+func writeHealth(rw http.ResponseWriter, status int, report *HealthReport) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Cache-Control", "no-store")
+	rw.WriteHeader(status)
+	data.ToJSON(report, rw)
+}
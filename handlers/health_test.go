@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestHealth_Ready(t *testing.T) {
+	ok := func(ctx context.Context) error { return nil }
+	down := func(ctx context.Context) error { return errors.New("down") }
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}
+	for name, tc := range map[string]struct {
+		critical, other CheckFunc
+		status          int
+		report          string
+	}{
+		"healthy":             {ok, ok, http.StatusOK, StatusOK},
+		"non critical failed": {ok, down, http.StatusOK, StatusDegraded},
+		"critical failed":     {down, ok, http.StatusServiceUnavailable, StatusFail},
+		"critical timed out":  {slow, down, http.StatusServiceUnavailable, StatusFail},
+	} {
+		h := NewHealth(hclog.NewNullLogger(), 10*time.Millisecond)
+		h.AddCheck("store", true, tc.critical)
+		h.AddCheck("currency", false, tc.other)
+
+		rw := serve(http.HandlerFunc(h.Ready), http.MethodGet, "/readyz", "", nil)
+		report := &HealthReport{}
+		if err := json.NewDecoder(rw.Body).Decode(report); err != nil {
+			t.Fatal(err)
+		}
+		if rw.Code != tc.status || report.Status != tc.report || len(report.Checks) != 2 {
+			t.Errorf("%s: expected %d %s, got %d %+v", name, tc.status, tc.report, rw.Code, report)
+		}
+	}
+
+	h := NewHealth(hclog.NewNullLogger(), time.Second)
+	h.AddCheck("store", true, down)
+	if rw := serve(http.HandlerFunc(h.Live), http.MethodGet, "/healthz", "", nil); rw.Code != http.StatusOK {
+		t.Fatalf("expected the liveness to ignore the checks, got %d", rw.Code)
+	}
+}
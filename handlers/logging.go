@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/CassioRoos/MicroseService/logging"
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+	"go.opentelemetry.io/otel/api/trace"
+)
+
+// HeaderRequestID is the header carrying the ID of the request,
+// it is sent back in the response
+const HeaderRequestID = "X-Request-ID"
+
+// requestID is the format of the request IDs accepted from the clients,
+// other IDs are replaced so they can not break the logs
+var requestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// MiddlewareRequestLog assigns an ID to every request, the ID sent by the
+// client in the X-Request-ID header is kept when it is valid. The logger of
+// the request, tagged with the ID, is added to the context for the handlers
+// and the repository, then one access log line is written per request
+func MiddlewareRequestLog(l hclog.Logger, router *mux.Router) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			id := r.Header.Get(HeaderRequestID)
+			if !requestID.MatchString(id) {
+				id = newRequestID()
+			}
+			rw.Header().Set(HeaderRequestID, id)
+
+			rl := l.With("request_id", id)
+			if sc := trace.SpanFromContext(r.Context()).SpanContext(); sc.HasTraceID() {
+				rl = rl.With("trace_id", sc.TraceID.String())
+			}
+			ctx := logging.WithRequestID(r.Context(), id)
+			ctx = logging.WithLogger(ctx, rl)
+			sr := &statusRecorder{ResponseWriter: rw, status: http.StatusOK}
+
+			next.ServeHTTP(sr, r.WithContext(ctx))
+
+			rl.Info("Request handled",
+				"method", r.Method,
+				"route", routeTemplate(router, r),
+				"path", r.URL.Path,
+				"status", sr.status,
+				"bytes", sr.bytes,
+				"duration", time.Since(start).String(),
+				"remote_addr", r.RemoteAddr,
+			)
+		})
+	}
+}
+
+// newRequestID returns a random ID of 32 hexadecimal characters
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// the clock is unique enough when the random source fails
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// logger returns the logger of the request, tagged with the request ID
+func (c *Cars) logger(r *http.Request) hclog.Logger {
+	return logging.FromContext(r.Context(), c.l)
+}
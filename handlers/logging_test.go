@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/CassioRoos/MicroseService/logging"
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestMiddlewareRequestLog_RequestID(t *testing.T) {
+	var seen string
+	sm := mux.NewRouter()
+	sm.HandleFunc("/cars", func(rw http.ResponseWriter, r *http.Request) {
+		seen = logging.RequestID(r.Context())
+	})
+	h := MiddlewareRequestLog(hclog.NewNullLogger(), sm)(sm)
+
+	// a valid ID sent by the client is kept
+	rw := serve(h, http.MethodGet, "/cars", "", map[string]string{HeaderRequestID: "client-42"})
+	if rw.Header().Get(HeaderRequestID) != "client-42" || seen != "client-42" {
+		t.Fatalf("expected the client ID, got %s in the response and %s in the context", rw.Header().Get(HeaderRequestID), seen)
+	}
+
+	// an ID that could break the logs is replaced
+	for _, id := range []string{"", "id with spaces", strings.Repeat("a", 129)} {
+		rw = serve(h, http.MethodGet, "/cars", "", map[string]string{HeaderRequestID: id})
+		got := rw.Header().Get(HeaderRequestID)
+		if got == id || len(got) != 32 || seen != got {
+			t.Fatalf("expected a new ID instead of %q, got %s in the response and %s in the context", id, got, seen)
+		}
+	}
+}
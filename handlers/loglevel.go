@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/CassioRoos/MicroseService/data"
+	"github.com/CassioRoos/MicroseService/logging"
+	"github.com/hashicorp/go-hclog"
+)
+
+// LevelSetter is a logger whose level can be changed while the service runs
+type LevelSetter interface {
+	Level() hclog.Level
+	SetLevel(level hclog.Level)
+}
+
+// LogLevel serves the admin endpoint reading and changing the level of the logs,
+// like turning on debug during an incident without a restart
+type LogLevel struct {
+	l     hclog.Logger
+	ls    LevelSetter
+	token string
+}
+
+// LogLevelDoc is the level of the logs
+// swagger:model
+type LogLevelDoc struct {
+	// trace, debug, info, warn or error
+	// required: true
+	// example: debug
+	Level string `json:"level"`
+}
+
+// NewLogLevel creates the admin endpoint, the requests must send the token
+// as a bearer token in the Authorization header. With an empty token every
+// request is refused, the endpoint is never open
+func NewLogLevel(l hclog.Logger, ls LevelSetter, token string) *LogLevel {
+	return &LogLevel{l: l, ls: ls, token: token}
+}
+
+// swagger:route GET /admin/log-level admin getLogLevel
+// Returns the level of the logs
+// responses:
+// 		200: logLevelResponse
+// 		401: errorResponse
+
+// GetLevel handles GET requests and returns the current level
+func (ll *LogLevel) GetLevel(rw http.ResponseWriter, r *http.Request) {
+	if !ll.authorized(rw, r) {
+		return
+	}
+	ll.write(rw)
+}
+
+// swagger:route PUT /admin/log-level admin setLogLevel
+// Changes the level of the logs until the service restarts
+// responses:
+// 		200: logLevelResponse
+// 		400: errorResponse
+// 		401: errorResponse
+
+// SetLevel handles PUT requests and changes the level of the logs
+func (ll *LogLevel) SetLevel(rw http.ResponseWriter, r *http.Request) {
+	if !ll.authorized(rw, r) {
+		return
+	}
+	doc := &LogLevelDoc{}
+	if err := data.FromJSON(doc, r.Body); err != nil {
+		writeProblem(rw, r, http.StatusBadRequest, fmt.Errorf("Unable to deserialize the log level: %s", err))
+		return
+	}
+	level, err := logging.ParseLevel(doc.Level)
+	if err != nil {
+		writeProblem(rw, r, http.StatusBadRequest, err)
+		return
+	}
+	// logged at warn so the change is seen whatever the levels
+	logging.FromContext(r.Context(), ll.l).Warn("Log level changed", "from", ll.ls.Level(), "to", level)
+	ll.ls.SetLevel(level)
+	ll.write(rw)
+}
+
+// authorized checks the bearer token, a 401 problem is written when it does not match
+func (ll *LogLevel) authorized(rw http.ResponseWriter, r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	token := strings.TrimPrefix(auth, "Bearer ")
+	if ll.token != "" && token != auth && subtle.ConstantTimeCompare([]byte(token), []byte(ll.token)) == 1 {
+		return true
+	}
+	rw.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+	writeProblem(rw, r, http.StatusUnauthorized, fmt.Errorf("A valid admin token is required"))
+	return false
+}
+
+func (ll *LogLevel) write(rw http.ResponseWriter) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Cache-Control", "no-store")
+	data.ToJSON(&LogLevelDoc{Level: ll.ls.Level().String()}, rw)
+}
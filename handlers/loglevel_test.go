@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// levelSetter records the level set by the handler
+type levelSetter struct {
+	mu    sync.Mutex
+	level hclog.Level
+}
+
+func (ls *levelSetter) Level() hclog.Level {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return ls.level
+}
+
+func (ls *levelSetter) SetLevel(level hclog.Level) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.level = level
+}
+
+func TestLogLevel_RequiresToken(t *testing.T) {
+	for _, token := range []string{"", "s3cret"} {
+		ls := &levelSetter{level: hclog.Info}
+		ll := NewLogLevel(hclog.NewNullLogger(), ls, token)
+
+		for _, auth := range []string{"", "Bearer ", "Bearer wrong", "s3cret"} {
+			rw := serve(http.HandlerFunc(ll.SetLevel), http.MethodPut, "/admin/log-level", `{"level":"trace"}`, map[string]string{"Authorization": auth})
+			if rw.Code != http.StatusUnauthorized || rw.Header().Get("WWW-Authenticate") == "" {
+				t.Fatalf("token %q, Authorization %q: expected 401, got %d", token, auth, rw.Code)
+			}
+			if rw = serve(http.HandlerFunc(ll.GetLevel), http.MethodGet, "/admin/log-level", "", map[string]string{"Authorization": auth}); rw.Code != http.StatusUnauthorized {
+				t.Fatalf("token %q, Authorization %q: expected 401 reading the level, got %d", token, auth, rw.Code)
+			}
+		}
+		if ls.Level() != hclog.Info {
+			t.Fatalf("expected the level unchanged, got %s", ls.Level())
+		}
+	}
+}
+
+func TestLogLevel_SetLevel(t *testing.T) {
+	ls := &levelSetter{level: hclog.Info}
+	ll := NewLogLevel(hclog.NewNullLogger(), ls, "s3cret")
+	auth := map[string]string{"Authorization": "Bearer s3cret"}
+
+	rw := serve(http.HandlerFunc(ll.SetLevel), http.MethodPut, "/admin/log-level", `{"level":"debug"}`, auth)
+	if rw.Code != http.StatusOK || rw.Body.String() != "{\"level\":\"debug\"}\n" || ls.Level() != hclog.Debug {
+		t.Fatalf("expected debug, got %d %s", rw.Code, rw.Body)
+	}
+
+	rw = serve(http.HandlerFunc(ll.GetLevel), http.MethodGet, "/admin/log-level", "", auth)
+	if rw.Code != http.StatusOK || rw.Body.String() != "{\"level\":\"debug\"}\n" {
+		t.Fatalf("expected debug, got %d %s", rw.Code, rw.Body)
+	}
+
+	for _, body := range []string{`{"level":"verbose"}`, `level=debug`} {
+		rw = serve(http.HandlerFunc(ll.SetLevel), http.MethodPut, "/admin/log-level", body, auth)
+		if rw.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", body, rw.Code)
+		}
+		decodeProblem(t, rw)
+	}
+	if ls.Level() != hclog.Debug {
+		t.Fatalf("expected debug after invalid levels, got %s", ls.Level())
+	}
+}
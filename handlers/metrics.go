@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// routeUnmatched is the route of the requests not matching any route,
+// so unknown paths do not create a time series each
+const routeUnmatched = "unmatched"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Number of HTTP requests by method, route and status code",
+	}, []string{"method", "route", "code"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of the HTTP requests by method and route",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+// statusRecorder keeps the status code and the size of the body written by the handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	sr.status = status
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+func (sr *statusRecorder) Write(b []byte) (int, error) {
+	n, err := sr.ResponseWriter.Write(b)
+	sr.bytes += n
+	return n, err
+}
+
+// MiddlewareMetrics records the count, latency and status code of the requests
+// handled by the router. The requests are labeled with the route template,
+// like /cars/{id}, instead of the path
+func MiddlewareMetrics(router *mux.Router) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			route := routeTemplate(router, r)
+			sr := &statusRecorder{ResponseWriter: rw, status: http.StatusOK}
+			start := time.Now()
+
+			next.ServeHTTP(sr, r)
+
+			httpDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+			httpRequests.WithLabelValues(r.Method, route, strconv.Itoa(sr.status)).Inc()
+		})
+	}
+}
+
+// routeTemplate returns the template of the route matching the request
+// without the patterns of the variables
+func routeTemplate(router *mux.Router, r *http.Request) string {
+	var match mux.RouteMatch
+	if !router.Match(r, &match) || match.Route == nil {
+		return routeUnmatched
+	}
+	tpl, err := match.Route.GetPathTemplate()
+	if err != nil {
+		return routeUnmatched
+	}
+	return stripPatterns(tpl)
+}
+
+// stripPatterns turns /cars/{id:[0-9]+} into /cars/{id}
+func stripPatterns(tpl string) string {
+	var b strings.Builder
+	depth := 0
+	skip := false
+	for _, ch := range tpl {
+		switch {
+		case ch == '{':
+			depth++
+			if depth == 1 {
+				skip = false
+				b.WriteRune(ch)
+				continue
+			}
+		case ch == '}':
+			depth--
+			if depth == 0 {
+				b.WriteRune(ch)
+				continue
+			}
+		case ch == ':' && depth == 1:
+			skip = true
+		}
+		if !skip || depth == 0 {
+			b.WriteRune(ch)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestStripPatterns(t *testing.T) {
+	for tpl, expected := range map[string]string{
+		"/cars":                        "/cars",
+		"/cars/{id:[0-9]+}":            "/cars/{id}",
+		"/cars/{id}":                   "/cars/{id}",
+		"/cars/{id:[0-9]{1,3}}/prices": "/cars/{id}/prices",
+		"/{kind:[a-z]+}/{id:[0-9]+}":   "/{kind}/{id}",
+		"/cars:batch":                  "/cars:batch",
+	} {
+		if got := stripPatterns(tpl); got != expected {
+			t.Errorf("%s: expected %s, got %s", tpl, expected, got)
+		}
+	}
+}
+
+func TestMiddlewareMetrics(t *testing.T) {
+	sm := mux.NewRouter()
+	getRouter := sm.Methods(http.MethodGet).Subrouter()
+	getRouter.HandleFunc("/cars/{id:[0-9]+}", func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusTeapot)
+	})
+	h := MiddlewareMetrics(sm)(sm)
+
+	for target, route := range map[string]string{"/cars/1": "/cars/{id}", "/cars/abc": routeUnmatched, "/unknown": routeUnmatched} {
+		if got := routeTemplate(sm, httptest.NewRequest(http.MethodGet, target, nil)); got != route {
+			t.Errorf("%s: expected the route %s, got %s", target, route, got)
+		}
+	}
+
+	teapots := httpRequests.WithLabelValues(http.MethodGet, "/cars/{id}", "418")
+	notFound := httpRequests.WithLabelValues(http.MethodGet, routeUnmatched, "404")
+	before, beforeNotFound := testutil.ToFloat64(teapots), testutil.ToFloat64(notFound)
+
+	serve(h, http.MethodGet, "/cars/1", "", nil)
+	serve(h, http.MethodGet, "/cars/2", "", nil)
+	serve(h, http.MethodGet, "/unknown", "", nil)
+
+	// both cars are counted under the template with the status of the handler
+	if got := testutil.ToFloat64(teapots) - before; got != 2 {
+		t.Fatalf("expected 2 requests labeled /cars/{id} and 418, got %v", got)
+	}
+	if got := testutil.ToFloat64(notFound) - beforeNotFound; got != 1 {
+		t.Fatalf("expected 1 unmatched request with 404, got %v", got)
+	}
+}
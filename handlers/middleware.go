@@ -5,23 +5,33 @@ import (
 	"fmt"
 	"github.com/CassioRoos/MicroseService/data"
 	"net/http"
+	"strings"
 )
 
 func (c Cars) MiddlewareValidateCar(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
 		car := &data.Car{}
 
-		if err := data.FromJSON(car, r.Body); err != nil {
-			c.l.Error("[ERROR] deserializing Car", err)
-			http.Error(rw, err.Error(), http.StatusBadRequest)
+		dec, err := c.e.ForContentType(r.Header.Get("Content-Type"))
+		if err != nil {
+			writeProblem(rw, r, http.StatusUnsupportedMediaType, fmt.Errorf("%s, supported media types are %s", err, strings.Join(c.e.MediaTypes(), ", ")))
+			return
+		}
+		if err := dec.Decode(car, r.Body); err != nil {
+			c.logger(r).Debug("Unable to deserialize car", "error", err)
+			writeProblem(rw, r, http.StatusBadRequest, err)
 			return
 		}
 
 		//Validate the car content before moving forward
-		errs := c.v.Validate(car)
+		errs, err := c.v.Validate(car)
+		if err != nil {
+			writeProblem(rw, r, http.StatusBadRequest, err)
+			return
+		}
 		if len(errs) != 0 {
-			c.l.Error("[ERROR] validating Car", errs)
-			http.Error(rw, fmt.Sprintf("Error reading the car: %s", errs), http.StatusBadRequest)
+			c.logger(r).Debug("Car is invalid", "errors", errs.Errors())
+			writeValidationProblem(rw, r, errs)
 			return
 		}
 		// add the car to the context
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/CassioRoos/MicroseService/data"
+)
+
+// parseCarQuery reads the paging, sorting and filter parameters from the URL
+// an error is returned when any parameter is malformed
+func parseCarQuery(r *http.Request) (data.CarQuery, error) {
+	v := r.URL.Query()
+	q := data.CarQuery{
+		Sort:  v.Get("sort"),
+		Color: v.Get("color"),
+		Name:  v.Get("name"),
+	}
+
+	var err error
+	if q.Limit, err = intParam(v, "limit"); err != nil {
+		return q, err
+	}
+	if q.Offset, err = intParam(v, "offset"); err != nil {
+		return q, err
+	}
+	if q.MinPrice, err = floatParam(v, "min_price"); err != nil {
+		return q, err
+	}
+	if q.MaxPrice, err = floatParam(v, "max_price"); err != nil {
+		return q, err
+	}
+	return q, q.Validate()
+}
+
+func intParam(v url.Values, name string) (int, error) {
+	s := v.Get(name)
+	if s == "" {
+		return 0, nil
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid %s, it should be an integer", name)
+	}
+	return i, nil
+}
+
+func floatParam(v url.Values, name string) (float64, error) {
+	s := v.Get(name)
+	if s == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid %s, it should be a number", name)
+	}
+	return f, nil
+}
+
+// setPageHeaders writes the total of cars into X-Total-Count and the links
+// to the previous and next pages into the Link header (RFC 8288)
+func setPageHeaders(rw http.ResponseWriter, r *http.Request, q data.CarQuery, total int) {
+	rw.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if q.Limit == 0 {
+		return
+	}
+
+	link := func(offset int, rel string) {
+		u := *r.URL
+		v := u.Query()
+		v.Set("offset", strconv.Itoa(offset))
+		v.Set("limit", strconv.Itoa(q.Limit))
+		u.RawQuery = v.Encode()
+		rw.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"%s\"", u.RequestURI(), rel))
+	}
+
+	if q.Offset+q.Limit < total {
+		link(q.Offset+q.Limit, "next")
+	}
+	if q.Offset > 0 {
+		prev := q.Offset - q.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		link(prev, "prev")
+	}
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/CassioRoos/MicroseService/data"
+)
+
+func TestGetListCars_Pagination(t *testing.T) {
+	sm, _ := newTestRouter(data.NewMemoryStore(data.Cars{
+		{ID: 1, Name: "Cruze", Price: data.Money{Amount: 3000, Currency: "EUR"}, LicensePlate: "IVP-5464"},
+		{ID: 2, Name: "Celta", Price: data.Money{Amount: 1000, Currency: "EUR"}, LicensePlate: "ABC-1234"},
+		{ID: 3, Name: "Onix", Price: data.Money{Amount: 2000, Currency: "EUR"}, LicensePlate: "ABC-4321"},
+	}))
+
+	rw := serve(sm, http.MethodGet, "/cars?limit=1&offset=1", "", nil)
+	if rw.Code != http.StatusOK || rw.Header().Get("X-Total-Count") != "3" {
+		t.Fatalf("expected 3 cars in total, got %d %s", rw.Code, rw.Header().Get("X-Total-Count"))
+	}
+	links := rw.Header()["Link"]
+	if len(links) != 2 || links[0] != `</cars?limit=1&offset=2>; rel="next"` || links[1] != `</cars?limit=1&offset=0>; rel="prev"` {
+		t.Fatalf("unexpected links %v", links)
+	}
+	var cars data.Cars
+	if err := data.FromJSON(&cars, rw.Body); err != nil || len(cars) != 1 || cars[0].ID != 2 {
+		t.Fatalf("expected the car 2, got %v %v", cars, err)
+	}
+
+	// the last page has no next link, the filters are kept in the links
+	rw = serve(sm, http.MethodGet, "/cars?limit=2&offset=1&sort=-price", "", nil)
+	links = rw.Header()["Link"]
+	if len(links) != 1 || links[0] != `</cars?limit=2&offset=0&sort=-price>; rel="prev"` {
+		t.Fatalf("unexpected links %v", links)
+	}
+
+	// without a limit every car is returned and there are no links
+	rw = serve(sm, http.MethodGet, "/cars?name=c", "", nil)
+	if rw.Header().Get("X-Total-Count") != "2" || rw.Header().Get("Link") != "" {
+		t.Fatalf("expected 2 cars without links, got %s %v", rw.Header().Get("X-Total-Count"), rw.Header()["Link"])
+	}
+}
+
+func TestGetListCars_InvalidQuery(t *testing.T) {
+	sm, _ := newTestRouter(nil)
+
+	for _, query := range []string{"limit=abc", "limit=-1", "offset=1.5", "min_price=cheap", "max_price=-10", "sort=color"} {
+		rw := serve(sm, http.MethodGet, "/cars?"+query, "", nil)
+		if rw.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rw.Code)
+			continue
+		}
+		decodeProblem(t, rw)
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"github.com/CassioRoos/MicroseService/data"
+)
+
+// swagger:route PATCH /cars/{id} cars patchCar
+// Partially update the car details using a JSON Merge Patch (application/merge-patch+json)
+// or a JSON Patch (application/json-patch+json) document
+//
+// Consumes:
+// - application/merge-patch+json
+// - application/json-patch+json
+//
+// responses:
+// 	200: carResponse
+// 	400: errorResponse
+// 	404: errorResponse
+// 	406: errorResponse
+// 	412: errorResponse
+// 	415: errorResponse
+// 	422: errorValidation
+// 	500: errorResponse
+
+// PatchCar handles PATCH requests, the patched car is validated before being stored.
+// When If-Match is sent the car is only patched if the tag matches its version
+func (c *Cars) PatchCar(rw http.ResponseWriter, r *http.Request) {
+	id := getCarId(r)
+	c.logger(r).Debug("Handle PATCH car", "id", id)
+	enc, ok := c.negotiate(rw, r)
+	if !ok {
+		return
+	}
+
+	mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mt != data.MergePatchType && mt != data.JSONPatchType) {
+		writeProblem(rw, r, http.StatusUnsupportedMediaType, data.ErrUnsupportedPatch)
+		return
+	}
+
+	patch, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeProblem(rw, r, http.StatusBadRequest, err)
+		return
+	}
+
+	car, err := c.cr.GetCarById(r.Context(), id, nil)
+	if err != nil {
+		c.writeError(rw, r, err)
+		return
+	}
+
+	if version := ifMatchVersion(r); version != 0 && version != car.Version {
+		c.writeError(rw, r, data.ErrVersionMismatch)
+		return
+	}
+
+	patched, err := data.ApplyPatch(*car, mt, patch)
+	if err != nil {
+		writeProblem(rw, r, http.StatusBadRequest, err)
+		return
+	}
+
+	//Validate the patched car before storing it
+	errs, err := c.v.Validate(&patched)
+	if err != nil {
+		writeProblem(rw, r, http.StatusBadRequest, err)
+		return
+	}
+	if len(errs) != 0 {
+		c.logger(r).Debug("Patched car is invalid", "errors", errs.Errors())
+		writeValidationProblem(rw, r, errs)
+		return
+	}
+
+	// the patch was applied to this version, the update fails if someone
+	// changed the car in the meantime
+	nc, err := c.cr.UpdateCar(r.Context(), patched, car.Version)
+	if err != nil {
+		c.writeError(rw, r, err)
+		return
+	}
+
+	rw.Header().Set("ETag", etag(nc, nil))
+	if err := enc.Encode(nc, rw); err != nil {
+		// should never happen, but will log it - Defense coding
+		c.logger(r).Error("Unable to serialize car", "error", err)
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/CassioRoos/MicroseService/data"
+)
+
+func decodeCar(t *testing.T, body []byte) *data.Car {
+	t.Helper()
+	car := &data.Car{}
+	if err := json.Unmarshal(body, car); err != nil {
+		t.Fatal(err)
+	}
+	return car
+}
+
+func TestPatchCar(t *testing.T) {
+	sm, _ := newTestRouter(nil)
+
+	rw := serve(sm, http.MethodPatch, "/cars/1", `{"color":"Red","description":null}`, map[string]string{"Content-Type": data.MergePatchType})
+	if rw.Code != http.StatusOK || rw.Header().Get("ETag") != `"2"` {
+		t.Fatalf("expected the merge patch applied, got %d %s", rw.Code, rw.Body)
+	}
+	if car := decodeCar(t, rw.Body.Bytes()); car.Color != "Red" || car.Name != "Cruze" || car.Price.Amount != 1000 {
+		t.Fatalf("expected only the color changed, got %+v", car)
+	}
+
+	patch := `[{"op":"test","path":"/color","value":"Red"},{"op":"replace","path":"/name","value":"Cruze LT"}]`
+	rw = serve(sm, http.MethodPatch, "/cars/1", patch, map[string]string{"Content-Type": data.JSONPatchType, "If-Match": `"2"`})
+	if rw.Code != http.StatusOK || rw.Header().Get("ETag") != `"3"` {
+		t.Fatalf("expected the JSON patch applied, got %d %s", rw.Code, rw.Body)
+	}
+	if car := decodeCar(t, rw.Body.Bytes()); car.Name != "Cruze LT" || car.Color != "Red" {
+		t.Fatalf("expected the name replaced, got %+v", car)
+	}
+}
+
+func TestPatchCar_Rejected(t *testing.T) {
+	sm, _ := newTestRouter(nil)
+
+	for name, tc := range map[string]struct {
+		body   string
+		header map[string]string
+		status int
+	}{
+		"plain json":    {`{"color":"Red"}`, map[string]string{"Content-Type": "application/json"}, http.StatusUnsupportedMediaType},
+		"invalid car":   {`{"license_plate":"invalid"}`, map[string]string{"Content-Type": data.MergePatchType}, http.StatusUnprocessableEntity},
+		"stale version": {`{"color":"Red"}`, map[string]string{"Content-Type": data.MergePatchType, "If-Match": `"7"`}, http.StatusPreconditionFailed},
+		"failed test":   {`[{"op":"test","path":"/name","value":"Onix"}]`, map[string]string{"Content-Type": data.JSONPatchType}, http.StatusBadRequest},
+	} {
+		rw := serve(sm, http.MethodPatch, "/cars/1", tc.body, tc.header)
+		if rw.Code != tc.status {
+			t.Errorf("%s: expected %d, got %d %s", name, tc.status, rw.Code, rw.Body)
+			continue
+		}
+		decodeProblem(t, rw)
+	}
+
+	// none of the patches was stored
+	if rw := serve(sm, http.MethodGet, "/cars/1", "", nil); rw.Header().Get("ETag") != `"1"` {
+		t.Fatalf("expected the car unchanged, got %s", rw.Header().Get("ETag"))
+	}
+	if rw := serve(sm, http.MethodPatch, "/cars/9", `{"color":"Red"}`, map[string]string{"Content-Type": data.MergePatchType}); rw.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown car, got %d", rw.Code)
+	}
+}
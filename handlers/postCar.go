@@ -7,19 +7,36 @@ import (
 )
 
 // swagger:route POST /cars cars createCars
-// Create a new car
+// Create a new car, the location of the new car is returned in the Location header
 //
 // responses:
 // 	201: carResponse
+// 	400: errorResponse
+// 	406: errorResponse
+// 	415: errorResponse
 // 	422: errorValidation
-// 	501: errorResponse
+// 	500: errorResponse
 
 // Create handles POST requests to add new cars
 func (c *Cars) PostCar(rw http.ResponseWriter, r *http.Request) {
-	c.l.Debug("Handle POST ")
-	rw.WriteHeader(http.StatusCreated)
-	rw.Header().Add("Content-Type", "application/json")
+	c.logger(r).Debug("Handle POST car")
+	enc, ok := c.negotiate(rw, r)
+	if !ok {
+		return
+	}
 	car := r.Context().Value(KeyCar{}).(data.Car)
-	c.cr.AddCar(&car)
-	c.l.Debug(fmt.Sprintf("Car %#v", car))
+	nc, err := c.cr.AddCar(r.Context(), &car)
+	if err != nil {
+		c.writeError(rw, r, err)
+		return
+	}
+	c.logger(r).Debug("Adding car", "car", fmt.Sprintf("%#v", nc))
+
+	rw.Header().Set("Location", fmt.Sprintf("/cars/%d", nc.ID))
+	rw.Header().Set("ETag", etag(nc, nil))
+	rw.WriteHeader(http.StatusCreated)
+	if err := enc.Encode(nc, rw); err != nil {
+		// should never happen, but will log it - Defense coding
+		c.logger(r).Error("Unable to serialize car", "error", err)
+	}
 }
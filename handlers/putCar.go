@@ -2,31 +2,47 @@ package handlers
 
 import (
 	"github.com/CassioRoos/MicroseService/data"
+	"github.com/gorilla/mux"
 	"net/http"
 )
 
-
-// swagger:route PUT /cars cars updateCar
-// update the car details
+// swagger:route PUT /cars/{id} cars updateCar
+// Replace the car details, every field is overwritten by the given car
 //
 // responses:
-// 	200: noContentResponse
+// 	200: carResponse
+// 	400: errorResponse
 // 	404: errorResponse
+// 	412: errorResponse
+// 	406: errorResponse
+// 	415: errorResponse
 // 	422: errorValidation
+// 	500: errorResponse
 
 // Update handles PUT request to update car
+// the id in the path takes precedence over the one in the body,
+// PUT /cars with the id in the body is kept for older clients.
+// When If-Match is sent the car is only updated if the tag matches its version
 func (c *Cars) UpdateCar(rw http.ResponseWriter, r *http.Request) {
 
-	c.l.Debug("Handle PUT Car")
-	car := r.Context().Value(KeyCar{}).(data.Car)
-	err := c.cr.UpdateCar(car)
-	if err == data.ErrCarNotFound {
-		http.Error(rw, err.Error(), http.StatusBadRequest)
+	c.logger(r).Debug("Handle PUT Car")
+	enc, ok := c.negotiate(rw, r)
+	if !ok {
 		return
 	}
-
+	car := r.Context().Value(KeyCar{}).(data.Car)
+	if _, ok := mux.Vars(r)["id"]; ok {
+		car.ID = getCarId(r)
+	}
+	nc, err := c.cr.UpdateCar(r.Context(), car, ifMatchVersion(r))
 	if err != nil {
-		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		c.writeError(rw, r, err)
 		return
 	}
+
+	rw.Header().Set("ETag", etag(nc, nil))
+	if err := enc.Encode(nc, rw); err != nil {
+		// should never happen, but will log it - Defense coding
+		c.logger(r).Error("Unable to serialize car", "error", err)
+	}
 }
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// MiddlewareTracing starts a span for every request handled by the router,
+// the span continues the trace of the W3C traceparent header sent by the client.
+// The spans are named by method and route template, like GET /cars/{id}
+func MiddlewareTracing(router *mux.Router) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return otelhttp.NewHandler(next, "http.server",
+			otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+				return r.Method + " " + routeTemplate(router, r)
+			}),
+		)
+	}
+}
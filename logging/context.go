@@ -0,0 +1,37 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+type keyLogger struct{}
+
+type keyRequestID struct{}
+
+// WithLogger returns a copy of the context carrying the logger,
+// like the logger of a request tagged with its request ID
+func WithLogger(ctx context.Context, l hclog.Logger) context.Context {
+	return context.WithValue(ctx, keyLogger{}, l)
+}
+
+// FromContext returns the logger carried by the context,
+// the fallback is returned when the context has none
+func FromContext(ctx context.Context, fallback hclog.Logger) hclog.Logger {
+	if l, ok := ctx.Value(keyLogger{}).(hclog.Logger); ok {
+		return l
+	}
+	return fallback
+}
+
+// WithRequestID returns a copy of the context carrying the ID of the request
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, keyRequestID{}, id)
+}
+
+// RequestID returns the ID of the request carried by the context, empty when there is none
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(keyRequestID{}).(string)
+	return id
+}
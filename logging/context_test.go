@@ -0,0 +1,31 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestFromContext(t *testing.T) {
+	fallback := hclog.NewNullLogger()
+	if l := FromContext(context.Background(), fallback); l != fallback {
+		t.Error("Expected the fallback logger when the context has none")
+	}
+
+	rl := hclog.New(&hclog.LoggerOptions{Name: "request"})
+	ctx := WithLogger(context.Background(), rl)
+	if l := FromContext(ctx, fallback); l != rl {
+		t.Error("Expected the logger of the context")
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	if id := RequestID(context.Background()); id != "" {
+		t.Errorf("Expected no request ID, got %q", id)
+	}
+	ctx := WithRequestID(context.Background(), "abc-123")
+	if id := RequestID(ctx); id != "abc-123" {
+		t.Errorf("Expected request ID abc-123, got %q", id)
+	}
+}
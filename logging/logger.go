@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// Formats of the log lines
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Config of the logger
+type Config struct {
+	// Name of the logger, written in every line
+	Name string
+	// trace, debug, info, warn or error
+	Level string
+	// json or text
+	Format string
+	// Layout of the time of the text lines, like 2006-01-02 15:04:05,
+	// the JSON lines always use RFC 3339
+	TimeFormat string
+	// File the lines are appended to, empty writes to the standard error
+	Path string
+}
+
+// Logger is the root logger of the service. Its level can be changed
+// while the service runs, the loggers created from it with With or Named,
+// like the loggers of the requests, follow the change
+type Logger struct {
+	hclog.Logger
+	level  int32
+	closer io.Closer
+}
+
+// New creates the logger of the config, Close must be called to release the file
+func New(cfg Config) (*Logger, error) {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Format != FormatJSON && cfg.Format != FormatText {
+		return nil, fmt.Errorf("Unknown log format %q, use %s or %s", cfg.Format, FormatJSON, FormatText)
+	}
+
+	var out io.Writer = os.Stderr
+	var closer io.Closer
+	if cfg.Path != "" {
+		f, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to open log file %s: %w", cfg.Path, err)
+		}
+		out, closer = f, f
+	}
+
+	l := hclog.New(&hclog.LoggerOptions{
+		Name:       cfg.Name,
+		Level:      level,
+		Output:     out,
+		JSONFormat: cfg.Format == FormatJSON,
+		TimeFormat: cfg.TimeFormat,
+	})
+	return &Logger{Logger: l, level: int32(level), closer: closer}, nil
+}
+
+// Level returns the current level of the logger
+func (l *Logger) Level() hclog.Level {
+	return hclog.Level(atomic.LoadInt32(&l.level))
+}
+
+// SetLevel changes the level of the logger and of the loggers created from it
+func (l *Logger) SetLevel(level hclog.Level) {
+	atomic.StoreInt32(&l.level, int32(level))
+	l.Logger.SetLevel(level)
+}
+
+// Close closes the log file, if any
+func (l *Logger) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
+// ParseLevel returns the level of the name, the name is not case sensitive
+func ParseLevel(name string) (hclog.Level, error) {
+	level := hclog.LevelFromString(name)
+	if level == hclog.NoLevel {
+		return hclog.NoLevel, fmt.Errorf("Unknown log level %q, use trace, debug, info, warn or error", name)
+	}
+	return level, nil
+}
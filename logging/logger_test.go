@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestNew_InvalidConfig(t *testing.T) {
+	if _, err := New(Config{Level: "loud", Format: FormatJSON}); err == nil {
+		t.Error("Expected an error for an unknown level")
+	}
+	if _, err := New(Config{Level: "info", Format: "xml"}); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestLogger_SetLevel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "api.log")
+
+	l, err := New(Config{Name: "test", Level: "INFO", Format: FormatText, Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the loggers of the requests are created before the level changes
+	rl := l.With("request_id", "abc")
+	rl.Debug("hidden")
+
+	l.SetLevel(hclog.Debug)
+	if l.Level() != hclog.Debug {
+		t.Errorf("Expected level debug, got %s", l.Level())
+	}
+	rl.Debug("shown")
+	l.Close()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "hidden") {
+		t.Error("Expected the debug line before the change to be dropped")
+	}
+	if !strings.Contains(string(b), "shown: request_id=abc") {
+		t.Errorf("Expected the debug line after the change, got %s", b)
+	}
+}
@@ -3,9 +3,13 @@ package main
 import (
 	"context"
 	"fmt"
+	"github.com/CassioRoos/MicroseService/config"
 	"github.com/CassioRoos/MicroseService/data"
+	"github.com/CassioRoos/MicroseService/grpc_circuitbreaker"
 	"github.com/CassioRoos/MicroseService/grpc_healthcheck"
 	"github.com/CassioRoos/MicroseService/handlers"
+	"github.com/CassioRoos/MicroseService/logging"
+	"github.com/CassioRoos/MicroseService/tracing"
 	"github.com/hashicorp/go-hclog"
 	"google.golang.org/grpc"
 	"net/http"
@@ -14,69 +18,159 @@ import (
 	"time"
 
 	"github.com/go-openapi/runtime/middleware"
-	"github.com/nicholasjackson/env"
 
 	protos "github.com/CassioRoos/grpc_currency/protos/currency"
 	health "github.com/CassioRoos/grpc_currency/protos/healthcheck"
 	gorilaHandlers "github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 )
 
-//A nice way to get the env variable, in this case, it will not raise an error when the value is not set, it will use default value instead
-var bindAddress = env.String("APP_PORT", false, ":8888", "Bind address for the server")
-var grpcPort = env.String("GRPC_PORT", false, "localhost:9098", "Bind address for GRPC server")
-
 func main() {
-	env.Parse()
-	log := hclog.New(&hclog.LoggerOptions{
-		Name:       "cassio.roos-api++>",
-		Level:      hclog.LevelFromString("DEBUG"),
-		JSONFormat: true,
-		TimeFormat: "01/01/2006 15:04:05",
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	log, err := logging.New(logging.Config{
+		Name:       cfg.Log.Name,
+		Level:      cfg.Log.Level,
+		Format:     cfg.Log.Format,
+		TimeFormat: cfg.Log.TimeFormat,
+		Path:       cfg.Log.Path,
 	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid log configuration:", err)
+		os.Exit(1)
+	}
+	defer log.Close()
+	// the secrets are redacted
+	log.Info("Effective configuration", cfg.KeyValues()...)
+	if cfg.Admin.Token == "" {
+		log.Warn("ADMIN_TOKEN is not set, the admin endpoints are disabled")
+	}
+	// the tracer provider is installed before the instrumented clients and handlers are created
+	shutdownTracing, err := tracing.Setup(tracing.Config{
+		Service:     "cars-api",
+		Exporter:    cfg.Trace.Exporter,
+		Path:        cfg.Trace.Path,
+		SampleRatio: cfg.Trace.SampleRatio,
+	})
+	if err != nil {
+		log.Error("Invalid tracing configuration", "error", err)
+		os.Exit(1)
+	}
+	defer shutdownTracing()
 	// THIS SHOULD NOT GO OUT IN PRODUCTION
 	// FOR TESTING PURPOSES  ONLY
-	log.Info("Establishing a connection to GRPC", "GRPC", *grpcPort)
-	conn, err := grpc.Dial(*grpcPort, grpc.WithInsecure())
+	log.Info("Establishing a connection to GRPC", "GRPC", cfg.Currency.Address)
+	// Dial does not wait for the connection, it only fails when the options are invalid.
+	// The connection is established in the background and retried while the server is down
+	// The trace context of the request is sent in the metadata of every call
+	conn, err := grpc.Dial(cfg.Currency.Address, grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(otelgrpc.StreamClientInterceptor()),
+	)
 	if err != nil {
-		log.Error("Unable to connect to GRPC Client on port")
-		panic(err)
+		log.Error("Invalid GRPC client configuration", "GRPC", cfg.Currency.Address, "error", err)
+		os.Exit(1)
 	}
 	// I was having problems with GRPCurl in my containers
 	// that`s why i create this work around
 	hc := health.NewHealthCheckClient(conn)
 	// simple struct with hclog
 	healthCheck := grpc_healthcheck.NewGrpcHealthCheck(log, hc)
-	// check 10 times for a OK status from GRPC, if the connection could not be established it will log an error and
-	// will exit with code 1
-	healthCheck.HealthCheck(10)
-	cc := protos.NewCurrencyClient(conn)
+	// the service starts even when the currency server is down, the cars can be
+	// managed and the currency endpoints return 503 until the server is healthy
+	go healthCheck.Watch(context.Background(), cfg.Currency.HealthInterval)
+	// conversions fail fast while the currency server is failing
+	cc := grpc_circuitbreaker.NewCircuitBreaker(protos.NewCurrencyClient(conn), log, cfg.Currency.BreakerThreshold, cfg.Currency.BreakerCoolDown)
 
 	//log := log.New(os.Stdout, "cassio.roos-api++>", log.LstdFlags)
 	validator := data.NewValidation()
-	repo := data.NewCarsRepository(cc, log)
-	car := handlers.NewCars(log, validator, repo)
+	store, err := data.NewCarStore(cfg.Store.Type, cfg.Store.Path)
+	if err != nil {
+		log.Error("Unable to open car store", "error", err)
+		os.Exit(1)
+	}
+	// the policy was validated with the config
+	policy, _ := data.ParseStalePolicy(cfg.Currency.RateStalePolicy)
+	rates := data.NewRateCache(cfg.Currency.RateMaxAge, policy)
+	repo := data.NewCarsRepository(cc, store, rates, cfg.Currency.Base, cfg.Currency.RateTimeout, log)
+	encoders := data.NewDefaultEncoders()
+	car := handlers.NewCars(log, validator, encoders, repo, healthCheck)
+
+	// only the store is critical, without the currency server the cars can still be managed
+	health := handlers.NewHealth(log, cfg.Server.ReadyTimeout)
+	health.AddCheck("store", true, repo.Ping)
+	health.AddCheck("currency", false, func(ctx context.Context) error {
+		if !healthCheck.Healthy() {
+			return data.ErrCurrencyUnavailable
+		}
+		return nil
+	})
+	health.AddCheck("rate_stream", false, func(ctx context.Context) error {
+		if state, since := repo.RateStreamState(); state != data.StreamConnected {
+			return fmt.Errorf("%w, %s since %s", data.ErrStreamNotConnected, state, since.Format(time.RFC3339))
+		}
+		return nil
+	})
+	health.AddCheck("rate_cache", false, func(ctx context.Context) error {
+		if rates.Len() == 0 {
+			return fmt.Errorf("No currency rate cached yet")
+		}
+		return nil
+	})
 	//Create a new serve mux and register the handler
 	sm := mux.NewRouter()
 
 	// SubRouter is a Handler of handler for GETs
 	getRouter := sm.Methods(http.MethodGet).Subrouter()
 	getRouter.HandleFunc("/cars", car.GetListCars)
-	getRouter.HandleFunc("/cars", car.GetListCars).Queries("currency", "{[A-Z]{3}}")
+	getRouter.HandleFunc("/cars", car.GetListCars).Queries("currency", "{currency:[A-Z]{3}(?:,[A-Z]{3})*}")
 	getRouter.HandleFunc("/cars/{id:[0-9]+}", car.GetCarById)
-	getRouter.HandleFunc("/cars/{id:[0-9]+}", car.GetCarById).Queries("currency", "{[A-Z]{3}}")
+	getRouter.HandleFunc("/cars/{id:[0-9]+}", car.GetCarById).Queries("currency", "{currency:[A-Z]{3}(?:,[A-Z]{3})*}")
+	getRouter.HandleFunc("/cars/export", car.ExportCars)
+	getRouter.HandleFunc("/currencies", car.ListCurrencies)
+	getRouter.HandleFunc("/healthz", health.Live)
+	getRouter.HandleFunc("/readyz", health.Ready)
+	getRouter.Handle("/metrics", promhttp.Handler())
+
+	// the level of the logs can be changed while the service runs,
+	// the admin endpoints are only served when a token protects them
+	logLevelHandler := handlers.NewLogLevel(log, log, cfg.Admin.Token)
+	if cfg.Admin.Token != "" {
+		getRouter.HandleFunc("/admin/log-level", logLevelHandler.GetLevel)
+	}
 
 	// SubRouter is a Handler of handler for PUTs
 	putRouter := sm.Methods(http.MethodPut).Subrouter()
 	// Regex will be validated and the id value will be available in the service side
+	putRouter.HandleFunc("/cars/{id:[0-9]+}", car.UpdateCar)
+	// kept for clients sending the id in the body
 	putRouter.HandleFunc("/cars", car.UpdateCar)
 	putRouter.Use(car.MiddlewareValidateCar)
 
+	// SubRouter is a Handler of handler for PATCHs, the patched car is validated by the handler
+	patchRouter := sm.Methods(http.MethodPatch).Subrouter()
+	patchRouter.HandleFunc("/cars/{id:[0-9]+}", car.PatchCar)
+
 	// SubRouter is a Handler of handler for POSTs
 	postRouter := sm.Methods(http.MethodPost).Subrouter()
 	postRouter.HandleFunc("/cars", car.PostCar)
 	postRouter.Use(car.MiddlewareValidateCar)
 
+	// SubRouter for batch imports, every car is validated by the handler
+	batchRouter := sm.Methods(http.MethodPost).Subrouter()
+	batchRouter.HandleFunc("/cars:batch", car.ImportCars)
+
+	// SubRouter for the admin changes, the body is not a car
+	if cfg.Admin.Token != "" {
+		adminRouter := sm.Methods(http.MethodPut).Subrouter()
+		adminRouter.HandleFunc("/admin/log-level", logLevelHandler.SetLevel)
+	}
+
 	deleteRouter := sm.Methods(http.MethodDelete).Subrouter()
 	deleteRouter.HandleFunc("/cars/{id:[0-9]+}", car.DeleteCar)
 
@@ -89,21 +183,30 @@ func main() {
 
 	// sm.Handle("/", car)
 	// we could allow a specific host like http://localhost:3000
-	ch := gorilaHandlers.CORS(gorilaHandlers.AllowedOrigins([]string{"*"}))
+	// every request is measured, the route template is used as label
+	metrics := handlers.MiddlewareMetrics(sm)
+	// every request gets a span, continuing the trace of the client when there is one
+	traces := handlers.MiddlewareTracing(sm)
+	// every request gets an ID and an access log line, the logger tagged with the ID is in the context
+	requestLog := handlers.MiddlewareRequestLog(log, sm)
+	// a dashboard on another origin reads the paging, the versions and the created cars from these headers
+	exposed := []string{handlers.HeaderRequestID, "X-Total-Count", "Link", "ETag", "Location"}
+	ch := gorilaHandlers.CORS(gorilaHandlers.AllowedOrigins(cfg.Server.CORSOrigins), gorilaHandlers.ExposedHeaders(exposed))
 	server := &http.Server{
-		Addr:         *bindAddress,
-		Handler:      ch(sm),
+		Addr:         cfg.Server.BindAddress,
+		Handler:      ch(traces(requestLog(metrics(sm)))),
 		ErrorLog:     log.StandardLogger(&hclog.StandardLoggerOptions{}),
-		WriteTimeout: 5 * time.Second,
-		ReadTimeout:  10 * time.Second,
-		IdleTimeout:  120 * time.Second,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
 
 	// log.Println("Listening to port: ", bindAddress)
 	go func() {
-		log.Info("Starting server on port %s\n", *bindAddress)
-		if err := server.ListenAndServe(); err != nil {
-			log.Error(fmt.Sprintf("Error while listening to port %s", *bindAddress))
+		log.Info("Starting server", "address", cfg.Server.BindAddress)
+		// ErrServerClosed is returned once Shutdown is called, main then flushes the traces
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("Unable to listen", "address", cfg.Server.BindAddress, "error", err)
 			os.Exit(1)
 		}
 	}()
@@ -115,11 +218,12 @@ func main() {
 
 	// WAIT until the signal comes. This is blocking, then will wait until something occurs
 	sig := <-sigChan
-	log.Info("Shutdown gracefully", sig)
+	log.Info("Shutting down gracefully", "signal", sig)
 
 	// get the general context to create a new
-	ct, _ := context.WithTimeout(context.Background(), 30*time.Second)
-	// gracefully shutdown the server, waiting max 30 seconds for current operations to complete
+	ct, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	// gracefully shutdown the server, waiting for current operations to complete within the shutdown timeout
 	server.Shutdown(ct)
 
 }
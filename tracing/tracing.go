@@ -0,0 +1,95 @@
+package tracing
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/exporters/stdout"
+	"go.opentelemetry.io/otel/propagators"
+	export "go.opentelemetry.io/otel/sdk/export/trace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+)
+
+// Exporters of the spans
+const (
+	// spans are not recorded, the trace context is still propagated
+	ExporterNone = "none"
+	// spans are written to the standard output
+	ExporterStdout = "stdout"
+	// spans are appended to a file, one JSON array per batch
+	ExporterFile = "file"
+)
+
+// Config of the tracing
+type Config struct {
+	// Name of the service in the spans
+	Service string
+	// none, stdout or file
+	Exporter string
+	// File the spans are appended to by the file exporter
+	Path string
+	// Fraction of the traces started by this service that are recorded,
+	// traces started by the caller follow the caller decision
+	SampleRatio float64
+}
+
+// Setup installs the global tracer provider and the W3C trace context and
+// baggage propagators. The returned function flushes the spans not exported
+// yet and closes the exporter, it must be called before the service exits
+func Setup(cfg Config) (func(), error) {
+	if cfg.SampleRatio < 0 || cfg.SampleRatio > 1 {
+		return nil, fmt.Errorf("Invalid trace sample ratio %v, it must be between 0 and 1", cfg.SampleRatio)
+	}
+	exp, closer, err := newExporter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	global.SetTextMapPropagator(otel.NewCompositeTextMapPropagator(propagators.TraceContext{}, propagators.Baggage{}))
+	if exp == nil {
+		return func() {}, nil
+	}
+
+	bsp := sdktrace.NewBatchSpanProcessor(exp)
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(bsp),
+		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))}),
+		sdktrace.WithResource(resource.New(semconv.ServiceNameKey.String(cfg.Service))),
+	)
+	global.SetTracerProvider(tp)
+
+	return func() {
+		bsp.Shutdown()
+		if closer != nil {
+			closer.Close()
+		}
+	}, nil
+}
+
+// newExporter creates the exporter of the config, none has no exporter.
+// The closer is set when the exporter owns a file
+func newExporter(cfg Config) (export.SpanExporter, io.Closer, error) {
+	switch cfg.Exporter {
+	case ExporterNone, "":
+		return nil, nil, nil
+	case ExporterStdout:
+		exp, err := stdout.NewExporter(stdout.WithWriter(os.Stdout), stdout.WithoutMetricExport())
+		return exp, nil, err
+	case ExporterFile:
+		f, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Unable to open trace file %s: %w", cfg.Path, err)
+		}
+		exp, err := stdout.NewExporter(stdout.WithWriter(f), stdout.WithoutMetricExport())
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		return exp, f, nil
+	}
+	return nil, nil, fmt.Errorf("Unknown trace exporter %q, use %s, %s or %s", cfg.Exporter, ExporterNone, ExporterStdout, ExporterFile)
+}
@@ -0,0 +1,50 @@
+package tracing
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/api/global"
+)
+
+func TestSetup_FileExporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tracing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "traces.json")
+
+	shutdown, err := Setup(Config{Service: "test", Exporter: ExporterFile, Path: path, SampleRatio: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := global.Tracer("test").Start(context.Background(), "GET /cars/{id}")
+	span.End()
+	// flushes the span to the file
+	shutdown()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "GET /cars/{id}") {
+		t.Errorf("Expected the span in the trace file, got %s", b)
+	}
+}
+
+func TestSetup_InvalidConfig(t *testing.T) {
+	if _, err := Setup(Config{Exporter: "zipkin", SampleRatio: 1}); err == nil {
+		t.Error("Expected an error for an unknown exporter")
+	}
+	if _, err := Setup(Config{Exporter: ExporterNone, SampleRatio: 2}); err == nil {
+		t.Error("Expected an error for a sample ratio greater than 1")
+	}
+	if _, err := Setup(Config{Exporter: ExporterFile, Path: filepath.Join("missing", "dir", "traces.json"), SampleRatio: 1}); err == nil {
+		t.Error("Expected an error when the trace file can not be opened")
+	}
+}